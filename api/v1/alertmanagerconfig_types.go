@@ -0,0 +1,86 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ConditionActive is true for the AlertmanagerConfig that is written to Cortex.
+	// Cortex keeps a single Alertmanager configuration per tenant.
+	ConditionActive = "Active"
+)
+
+// AlertmanagerConfigSpec defines the desired Alertmanager configuration of the tenant.
+// The sections use the format of the Alertmanager configuration file.
+type AlertmanagerConfigSpec struct {
+	// Global is the global section of the Alertmanager configuration.
+	Global *apiextensionsv1.JSON `json:"global,omitempty"`
+	// Route is the root of the routing tree.
+	Route *apiextensionsv1.JSON `json:"route"`
+	// Receivers are the notification integrations alerts are routed to.
+	Receivers []apiextensionsv1.JSON `json:"receivers"`
+	// InhibitRules mute alerts while other alerts are firing.
+	InhibitRules []apiextensionsv1.JSON `json:"inhibit_rules,omitempty"`
+	// Templates are notification template files, keyed by file name.
+	Templates map[string]string `json:"templates,omitempty"`
+}
+
+// AlertmanagerConfigStatus defines the observed state of AlertmanagerConfig
+type AlertmanagerConfigStatus struct {
+	SyncStatus string `json:"sync_status,omitempty"`
+	// LastSyncTime is when the configuration was last written to Cortex.
+	LastSyncTime *metav1.Time `json:"last_sync_time,omitempty"`
+	// Tenant is the Cortex tenant the configuration is written to.
+	Tenant string `json:"tenant,omitempty"`
+	// Conditions describe the current state of the AlertmanagerConfig.
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:shortName=camc,categories=cortex
+//+kubebuilder:printcolumn:name="Status",type=string,JSONPath=`.status.sync_status`
+//+kubebuilder:printcolumn:name="Tenant",type=string,JSONPath=`.status.tenant`
+//+kubebuilder:printcolumn:name="Last Sync",type=date,JSONPath=`.status.last_sync_time`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// AlertmanagerConfig is the Schema for the alertmanagerconfigs API
+type AlertmanagerConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AlertmanagerConfigSpec   `json:"spec,omitempty"`
+	Status AlertmanagerConfigStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// AlertmanagerConfigList contains a list of AlertmanagerConfig
+type AlertmanagerConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AlertmanagerConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AlertmanagerConfig{}, &AlertmanagerConfigList{})
+}
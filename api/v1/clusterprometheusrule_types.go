@@ -0,0 +1,89 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// RuleObject is implemented by PrometheusRule and ClusterPrometheusRule,
+// whose rule groups are synced to Cortex alike.
+// +kubebuilder:object:generate=false
+type RuleObject interface {
+	metav1.Object
+	runtime.Object
+
+	RuleSpec() *PrometheusRuleSpec
+	RuleStatus() *PrometheusRuleStatus
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster,shortName=ccpr,categories=cortex
+//+kubebuilder:printcolumn:name="Status",type=string,JSONPath=`.status.sync_status`
+//+kubebuilder:printcolumn:name="Groups",type=integer,JSONPath=`.status.groups`
+//+kubebuilder:printcolumn:name="Rules",type=integer,JSONPath=`.status.rules`
+//+kubebuilder:printcolumn:name="Cortex Namespace",type=string,JSONPath=`.status.cortex_namespace`,priority=1
+//+kubebuilder:printcolumn:name="Tenant",type=string,JSONPath=`.status.tenant`,priority=1
+//+kubebuilder:printcolumn:name="Firing",type=integer,JSONPath=`.status.alerts.firing`
+//+kubebuilder:printcolumn:name="Pending",type=integer,JSONPath=`.status.alerts.pending`
+//+kubebuilder:printcolumn:name="Last Sync",type=date,JSONPath=`.status.last_sync_time`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ClusterPrometheusRule is the Schema for the clusterprometheusrules API.
+// It holds platform-wide rules that do not belong to any namespace.
+type ClusterPrometheusRule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PrometheusRuleSpec   `json:"spec,omitempty"`
+	Status PrometheusRuleStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ClusterPrometheusRuleList contains a list of ClusterPrometheusRule
+type ClusterPrometheusRuleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterPrometheusRule `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterPrometheusRule{}, &ClusterPrometheusRuleList{})
+}
+
+// RuleSpec returns the spec of the PrometheusRule.
+func (in *PrometheusRule) RuleSpec() *PrometheusRuleSpec {
+	return &in.Spec
+}
+
+// RuleStatus returns the status of the PrometheusRule.
+func (in *PrometheusRule) RuleStatus() *PrometheusRuleStatus {
+	return &in.Status
+}
+
+// RuleSpec returns the spec of the ClusterPrometheusRule.
+func (in *ClusterPrometheusRule) RuleSpec() *PrometheusRuleSpec {
+	return &in.Spec
+}
+
+// RuleStatus returns the status of the ClusterPrometheusRule.
+func (in *ClusterPrometheusRule) RuleStatus() *PrometheusRuleStatus {
+	return &in.Status
+}
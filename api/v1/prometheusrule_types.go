@@ -21,10 +21,113 @@ import (
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
+const (
+	// DisabledGroupsAnnotation lists, comma separated, the rule groups not to send to Cortex.
+	DisabledGroupsAnnotation = "monitoring.bolinda.digital/disabled-groups"
+	// CortexNamespaceAnnotation overrides the Cortex namespace the rule groups are written to,
+	// e.g. to adopt an existing namespace that does not follow the naming scheme.
+	CortexNamespaceAnnotation = "monitoring.bolinda.digital/cortex-namespace"
+	// PausedAnnotation set to "true" stops the operator from changing the rules in Cortex.
+	PausedAnnotation = "monitoring.bolinda.digital/paused"
+	// ConfigMapVersionAnnotation is the resource version of the ConfigMap a PrometheusRule was generated from.
+	ConfigMapVersionAnnotation = "monitoring.bolinda.digital/configmap-version"
+
+	// ConditionPaused is true while the operator leaves the rules in Cortex untouched.
+	ConditionPaused = "Paused"
+	// ConditionTestsPassed is false while unit tests of the PrometheusRule fail.
+	ConditionTestsPassed = "TestsPassed"
+	// ConditionHealthy is false while the Cortex ruler reports failing or slow rule evaluations.
+	ConditionHealthy = "Healthy"
+	// ConditionPolicyCompliant is false while rules of the PrometheusRule violate a RulePolicy.
+	ConditionPolicyCompliant = "PolicyCompliant"
+	// ConditionQuotaExceeded is true while the rule groups do not fit the rule quotas and are not synced.
+	ConditionQuotaExceeded = "QuotaExceeded"
+)
+
+// DeletionPolicy decides what happens to the rules in Cortex when a PrometheusRule is deleted.
+// +kubebuilder:validation:Enum=Delete;Retain;Orphan
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete deletes the Cortex namespace of the PrometheusRule.
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	// DeletionPolicyRetain keeps the Cortex namespace, the finalizer is removed without touching Cortex.
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+	// DeletionPolicyOrphan keeps the Cortex namespace and does not hold a finalizer at all,
+	// so deletion never waits for the operator.
+	DeletionPolicyOrphan DeletionPolicy = "Orphan"
+)
+
+// Backend is the ruler a PrometheusRule is synced to.
+// +kubebuilder:validation:Enum=Cortex;Loki
+type Backend string
+
+const (
+	// BackendCortex evaluates PromQL rules in the Cortex ruler.
+	BackendCortex Backend = "Cortex"
+	// BackendLoki evaluates LogQL rules in the Loki ruler.
+	BackendLoki Backend = "Loki"
+)
+
 // PrometheusRuleSpec contains specification parameters for a Rule.
 type PrometheusRuleSpec struct {
 	// Content of Prometheus rule file
 	Groups []RuleGroup `json:"groups,omitempty"`
+	// Templates are RuleTemplates in the same namespace whose instantiated rule groups are synced along with Groups.
+	// Cluster-scoped rules cannot use templates.
+	Templates []TemplateInstance `json:"templates,omitempty"`
+	// DeletionPolicy overrides the deletion policy configured for the operator.
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+	// Tests are promtool style unit tests for Groups. Failing tests block the sync to Cortex.
+	Tests []RuleTest `json:"tests,omitempty"`
+	// Backend is the ruler the rule groups are synced to. Defaults to Cortex.
+	// Rules of the Loki backend are LogQL expressions; unit tests are not supported for them.
+	Backend Backend `json:"backend,omitempty"`
+}
+
+// RuleTest is a unit test for the rule groups of a PrometheusRule, in the format of promtool test rules.
+type RuleTest struct {
+	Name string `json:"name,omitempty"`
+	// Interval between the values of the input series. Defaults to 1m.
+	Interval       string            `json:"interval,omitempty"`
+	InputSeries    []InputSeries     `json:"input_series,omitempty"`
+	ExternalLabels map[string]string `json:"external_labels,omitempty"`
+	AlertRuleTests []AlertTestCase   `json:"alert_rule_test,omitempty"`
+	PromQLTests    []PromQLTestCase  `json:"promql_expr_test,omitempty"`
+}
+
+// InputSeries is a series and its values in expanding notation, e.g. '1+1x10'.
+type InputSeries struct {
+	Series string `json:"series"`
+	Values string `json:"values"`
+}
+
+// AlertTestCase lists the alerts expected to fire for an alerting rule at a point in time.
+type AlertTestCase struct {
+	EvalTime  string          `json:"eval_time"`
+	Alertname string          `json:"alertname"`
+	ExpAlerts []ExpectedAlert `json:"exp_alerts,omitempty"`
+}
+
+// ExpectedAlert is a firing alert expected by an AlertTestCase.
+type ExpectedAlert struct {
+	ExpLabels      map[string]string `json:"exp_labels,omitempty"`
+	ExpAnnotations map[string]string `json:"exp_annotations,omitempty"`
+}
+
+// PromQLTestCase lists the samples an expression is expected to return at a point in time.
+type PromQLTestCase struct {
+	Expr       string           `json:"expr"`
+	EvalTime   string           `json:"eval_time"`
+	ExpSamples []ExpectedSample `json:"exp_samples,omitempty"`
+}
+
+// ExpectedSample is a sample expected by a PromQLTestCase.
+type ExpectedSample struct {
+	// Labels of the sample in series notation, e.g. 'up{job="api"}'.
+	Labels string `json:"labels"`
+	// Value of the sample, as a string to avoid floating point values in the API.
+	Value string `json:"value"`
 }
 
 // RuleGroup is a list of sequentially evaluated recording and alerting rules.
@@ -32,6 +135,18 @@ type RuleGroup struct {
 	Name     string `json:"name" `
 	Interval string `json:"interval,omitempty"`
 	Rules    []Rule `json:"rules"`
+	// Limit is the number of alerts an alerting rule and series a recording rule can produce. 0 is no limit.
+	Limit int `json:"limit,omitempty"`
+	// QueryOffset delays the evaluation of the group by the given duration.
+	QueryOffset string `json:"query_offset,omitempty"`
+	// EvaluationDelay is the Cortex name of QueryOffset, for rulers that do not know query_offset.
+	EvaluationDelay string `json:"evaluation_delay,omitempty"`
+	// SourceTenants are the tenants the rules of a federated group query.
+	SourceTenants []string `json:"source_tenants,omitempty"`
+	// AlignEvaluationTimeOnInterval aligns the evaluation of the group to multiples of its interval.
+	AlignEvaluationTimeOnInterval bool `json:"align_evaluation_time_on_interval,omitempty"`
+	// Disabled groups are not sent to Cortex.
+	Disabled bool `json:"disabled,omitempty"`
 }
 
 // Rule describes an alerting or recording rule.
@@ -42,15 +157,92 @@ type Rule struct {
 	For         string             `json:"for,omitempty"`
 	Labels      map[string]string  `json:"labels,omitempty"`
 	Annotations map[string]string  `json:"annotations,omitempty"`
+	// KeepFiringFor keeps an alert firing for the given duration after its condition cleared.
+	KeepFiringFor string `json:"keep_firing_for,omitempty"`
+	// Disabled rules are not sent to Cortex.
+	Disabled bool `json:"disabled,omitempty"`
 }
 
 // PrometheusRuleStatus defines the observed state of PrometheusRule
 type PrometheusRuleStatus struct {
 	SyncStatus string `json:"sync_status,omitempty"`
+	// LastSyncTime is when the rule groups were last written to Cortex.
+	LastSyncTime *metav1.Time `json:"last_sync_time,omitempty"`
+	// SyncedChecksum identifies the rule groups and tests last written to Cortex, and the tenant and ruler
+	// they were written to, so the tests do not run again while they are unchanged.
+	SyncedChecksum string `json:"synced_checksum,omitempty"`
+	// CortexNamespace is the Cortex namespace the rule groups are written to.
+	CortexNamespace string `json:"cortex_namespace,omitempty"`
+	// Tenant is the Cortex tenant the rule groups are written to.
+	Tenant string `json:"tenant,omitempty"`
+	// Groups is the number of rule groups sent to Cortex.
+	Groups int `json:"groups,omitempty"`
+	// Rules is the number of rules sent to Cortex.
+	Rules int `json:"rules,omitempty"`
+	// RewrittenRules lists the rules, as group/rule, whose expression was rewritten
+	// to enforce the namespace label matcher.
+	RewrittenRules []string `json:"rewritten_rules,omitempty"`
+	// Skipped lists the disabled groups and rules, as group or group/rule, that are not sent to Cortex.
+	Skipped []string `json:"skipped,omitempty"`
+	// TestFailures lists the failed unit tests of the last sync.
+	TestFailures []string `json:"test_failures,omitempty"`
+	// PolicyViolations lists the rules that do not meet a RulePolicy. They block the sync to Cortex.
+	PolicyViolations []PolicyViolation `json:"policy_violations,omitempty"`
+	// Diff is the truncated unified diff between the desired rule groups and Cortex,
+	// set when a sync fails or, in dry-run mode, when the rules drifted.
+	Diff string `json:"diff,omitempty"`
+	// Health is the evaluation state reported by the Cortex ruler at the last health check.
+	Health *RuleHealthStatus `json:"health,omitempty"`
+	// Alerts summarizes the active alerts of the alerting rules at the last health check.
+	Alerts *AlertSummary `json:"alerts,omitempty"`
+	// Conditions describe the current state of the PrometheusRule.
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// RuleHealthStatus lists the rules and groups the Cortex ruler has trouble evaluating.
+type RuleHealthStatus struct {
+	LastChecked    metav1.Time     `json:"last_checked,omitempty"`
+	UnhealthyRules []UnhealthyRule `json:"unhealthy_rules,omitempty"`
+	SlowGroups     []SlowGroup     `json:"slow_groups,omitempty"`
+}
+
+// UnhealthyRule is a rule whose last evaluation failed.
+type UnhealthyRule struct {
+	Group          string       `json:"group"`
+	Rule           string       `json:"rule"`
+	LastError      string       `json:"last_error,omitempty"`
+	LastEvaluation *metav1.Time `json:"last_evaluation,omitempty"`
+}
+
+// SlowGroup is a rule group whose last evaluation took longer than its interval.
+type SlowGroup struct {
+	Name           string `json:"name"`
+	EvaluationTime string `json:"evaluation_time"`
+	Interval       string `json:"interval"`
+}
+
+// AlertSummary counts the active alerts of a PrometheusRule.
+type AlertSummary struct {
+	Firing  int `json:"firing"`
+	Pending int `json:"pending"`
+	// FiringAlerts lists the names of the firing alerts.
+	FiringAlerts []string `json:"firing_alerts,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:resource:shortName=cpr,categories=cortex
+//+kubebuilder:printcolumn:name="Status",type=string,JSONPath=`.status.sync_status`
+//+kubebuilder:printcolumn:name="Groups",type=integer,JSONPath=`.status.groups`
+//+kubebuilder:printcolumn:name="Rules",type=integer,JSONPath=`.status.rules`
+//+kubebuilder:printcolumn:name="Cortex Namespace",type=string,JSONPath=`.status.cortex_namespace`,priority=1
+//+kubebuilder:printcolumn:name="Tenant",type=string,JSONPath=`.status.tenant`,priority=1
+//+kubebuilder:printcolumn:name="Firing",type=integer,JSONPath=`.status.alerts.firing`
+//+kubebuilder:printcolumn:name="Pending",type=integer,JSONPath=`.status.alerts.pending`
+//+kubebuilder:printcolumn:name="Last Sync",type=date,JSONPath=`.status.last_sync_time`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // PrometheusRule is the Schema for the prometheusrules API
 type PrometheusRule struct {
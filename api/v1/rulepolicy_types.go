@@ -0,0 +1,85 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RulePolicySpec defines the requirements rules have to meet.
+type RulePolicySpec struct {
+	// NamespaceSelector selects the namespaces whose PrometheusRules the policy applies to.
+	// If unset, the policy applies to all PrometheusRules and to ClusterPrometheusRules.
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// RequiredLabels must be set on every alerting rule.
+	RequiredLabels []string `json:"requiredLabels,omitempty"`
+	// RequiredAnnotations must be set on every alerting rule.
+	RequiredAnnotations []string `json:"requiredAnnotations,omitempty"`
+	// AllowedSeverities, if set, are the values the severity label of an alerting rule may have.
+	AllowedSeverities []string `json:"allowedSeverities,omitempty"`
+	// MinFor is the shortest for duration of an alerting rule, e.g. 1m.
+	MinFor string `json:"minFor,omitempty"`
+	// ForbiddenFunctions are PromQL functions no rule may call, e.g. absent or holt_winters.
+	ForbiddenFunctions []string `json:"forbiddenFunctions,omitempty"`
+	// Quota limits the rule groups and rules of each selected namespace.
+	// If several quotas apply, including the one of the operator, the strictest limits win.
+	Quota *RuleQuota `json:"quota,omitempty"`
+}
+
+// RuleQuota limits the rule groups and rules synced to the ruler. Zero values are unlimited.
+type RuleQuota struct {
+	// +kubebuilder:validation:Minimum=0
+	MaxGroups int `json:"maxGroups,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	MaxRulesPerGroup int `json:"maxRulesPerGroup,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	MaxRules int `json:"maxRules,omitempty"`
+}
+
+// PolicyViolation is a rule that does not meet a RulePolicy.
+type PolicyViolation struct {
+	Policy string `json:"policy"`
+	Group  string `json:"group"`
+	// Rule is the alert or record name of the rule.
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster,categories=cortex
+
+// RulePolicy is the Schema for the rulepolicies API.
+// Rules violating a policy are rejected by the admission webhook and not synced to Cortex.
+type RulePolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec RulePolicySpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// RulePolicyList contains a list of RulePolicy
+type RulePolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RulePolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RulePolicy{}, &RulePolicyList{})
+}
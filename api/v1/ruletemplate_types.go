@@ -0,0 +1,71 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RuleTemplateSpec defines parameterized rule groups.
+type RuleTemplateSpec struct {
+	// Parameters are the values an instance of the template may set.
+	Parameters []TemplateParameter `json:"parameters,omitempty"`
+	// Groups are instantiated by expanding Go templates delimited by [[ and ]], e.g. [[ .service ]],
+	// in every name, expression, duration, label and annotation. The {{ }} templates of alert
+	// annotations are left for Cortex.
+	Groups []RuleGroup `json:"groups"`
+}
+
+// TemplateParameter is a value a RuleTemplate is instantiated with.
+type TemplateParameter struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Default is used if an instance does not set the parameter. Parameters without default are required.
+	Default *string `json:"default,omitempty"`
+}
+
+// TemplateInstance instantiates a RuleTemplate in the namespace of a PrometheusRule.
+type TemplateInstance struct {
+	// Name of the RuleTemplate.
+	Name string `json:"name"`
+	// Values of the template parameters.
+	Values map[string]string `json:"values,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:shortName=crt,categories=cortex
+
+// RuleTemplate is the Schema for the ruletemplates API
+type RuleTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec RuleTemplateSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// RuleTemplateList contains a list of RuleTemplate
+type RuleTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RuleTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RuleTemplate{}, &RuleTemplateList{})
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SilenceSpec defines the alerts to silence and for how long.
+type SilenceSpec struct {
+	// Matchers select the alerts to silence.
+	// +kubebuilder:validation:MinItems=1
+	Matchers []SilenceMatcher `json:"matchers"`
+	StartsAt metav1.Time      `json:"startsAt"`
+	EndsAt   metav1.Time      `json:"endsAt"`
+	// CreatedBy names the author of the silence.
+	CreatedBy string `json:"createdBy"`
+	Comment   string `json:"comment"`
+}
+
+// SilenceMatcher is a label matcher of a Silence.
+type SilenceMatcher struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	// IsRegex matches Value as a regular expression.
+	IsRegex bool `json:"isRegex,omitempty"`
+	// IsEqual set to false negates the matcher. Defaults to true.
+	IsEqual *bool `json:"isEqual,omitempty"`
+}
+
+// SilenceStatus defines the observed state of Silence
+type SilenceStatus struct {
+	SyncStatus string `json:"sync_status,omitempty"`
+	// SilenceID is the ID of the silence in the Alertmanager.
+	SilenceID string `json:"silence_id,omitempty"`
+	// State is the state of the silence as reported by the Alertmanager: pending, active or expired.
+	State string `json:"state,omitempty"`
+	// Conditions describe the current state of the Silence.
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:categories=cortex
+//+kubebuilder:printcolumn:name="State",type=string,JSONPath=`.status.state`
+//+kubebuilder:printcolumn:name="Starts",type=date,JSONPath=`.spec.startsAt`
+//+kubebuilder:printcolumn:name="Ends",type=date,JSONPath=`.spec.endsAt`
+//+kubebuilder:printcolumn:name="Silence ID",type=string,JSONPath=`.status.silence_id`,priority=1
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// Silence is the Schema for the silences API
+type Silence struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SilenceSpec   `json:"spec,omitempty"`
+	Status SilenceStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// SilenceList contains a list of Silence
+type SilenceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Silence `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Silence{}, &SilenceList{})
+}
@@ -0,0 +1,305 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	monitoringv1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+	"github.com/bolindalabs/cortex-alert-operator/controllers/cortex"
+)
+
+const alertmanagerConfigFinalizerName = "alertmanagerconfig.monitoring.bolinda.digital"
+
+// AlertmanagerConfigReconciler reconciles an AlertmanagerConfig object
+type AlertmanagerConfigReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+
+	Cortex *cortex.Client
+	// Tenant is the Cortex tenant the configuration is written to.
+	Tenant string
+
+	// Paused stops all writes to Cortex for every AlertmanagerConfig.
+	Paused bool
+}
+
+//+kubebuilder:rbac:groups=monitoring.bolinda.digital,resources=alertmanagerconfigs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=monitoring.bolinda.digital,resources=alertmanagerconfigs/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=monitoring.bolinda.digital,resources=alertmanagerconfigs/finalizers,verbs=update
+
+// Reconcile writes the Alertmanager configuration of the tenant to Cortex.
+// Cortex keeps a single configuration per tenant, so only the oldest AlertmanagerConfig
+// is written; all others are reported as conflicting until it is deleted.
+func (r *AlertmanagerConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("alertmanagerconfig", req.NamespacedName)
+
+	var config monitoringv1.AlertmanagerConfig
+	if err := r.Get(ctx, req.NamespacedName, &config); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			return ctrl.Result{}, nil
+		}
+
+		log.Error(err, "unable to fetch AlertmanagerConfig")
+		return ctrl.Result{}, err
+	}
+
+	active, err := r.activeConfig(ctx)
+	if err != nil {
+		log.Error(err, "unable to list AlertmanagerConfigs")
+		return ctrl.Result{}, err
+	}
+
+	hasFinalizer := containsString(config.ObjectMeta.Finalizers, alertmanagerConfigFinalizerName)
+	isDeletionScheduled := !config.ObjectMeta.DeletionTimestamp.IsZero()
+
+	switch {
+	case !hasFinalizer && !isDeletionScheduled:
+		if err := r.addFinalizer(ctx, config, log); err != nil {
+			log.Error(err, "unable to add finalizer")
+			return ctrl.Result{}, err
+		}
+		// Metadata updates are filtered out, so the configuration is synced on the next pass.
+		return ctrl.Result{Requeue: true}, nil
+	case r.isPaused(config):
+		log.Info("reconciliation paused, leaving Cortex untouched")
+		if err := r.patchStatus(ctx, config, func(status *monitoringv1.AlertmanagerConfigStatus) {
+			meta.SetStatusCondition(&status.Conditions, pausedCondition(true))
+		}); err != nil {
+			log.Error(err, "unable to set status")
+			return ctrl.Result{}, err
+		}
+	case isDeletionScheduled:
+		// Another AlertmanagerConfig takes over the tenant, so the configuration is only deleted with the last one.
+		if active == nil {
+			if err := r.Cortex.DeleteAlertmanagerConfig(log); err != nil && !errors.Is(err, cortex.ErrResourceNotFound) {
+				log.Error(err, "unable to delete Alertmanager configuration")
+				return ctrl.Result{}, err
+			}
+		}
+		if hasFinalizer {
+			if err := r.removeFinalizer(ctx, config, log); err != nil {
+				log.Error(err, "unable to remove finalizer")
+				return ctrl.Result{}, err
+			}
+		}
+	case active.Namespace != config.Namespace || active.Name != config.Name:
+		message := fmt.Sprintf("the Alertmanager configuration of the tenant is managed by %s/%s", active.Namespace, active.Name)
+		log.Info("conflicting AlertmanagerConfig, not syncing", "active", active.Namespace+"/"+active.Name)
+		r.Recorder.Event(&config, corev1.EventTypeWarning, "Conflict", message)
+
+		if err := r.patchStatus(ctx, config, func(status *monitoringv1.AlertmanagerConfigStatus) {
+			status.SyncStatus = "conflict"
+			meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+				Type:    monitoringv1.ConditionActive,
+				Status:  metav1.ConditionFalse,
+				Reason:  "Conflict",
+				Message: message,
+			})
+		}); err != nil {
+			log.Error(err, "unable to set status")
+			return ctrl.Result{}, err
+		}
+	default:
+		// Unchanged configurations are not written again, so LastSyncTime records the last change in Cortex.
+		desired, err := cortex.NewAlertmanagerConfig(config.Spec)
+		var upToDate bool
+		if err == nil {
+			upToDate, err = r.isUpToDate(log, desired)
+		}
+		if err == nil && !upToDate {
+			err = r.Cortex.SetAlertmanagerConfig(log, desired)
+		}
+		if err != nil {
+			log.Error(err, "unable to set Alertmanager configuration")
+
+			if err := r.patchStatus(ctx, config, func(status *monitoringv1.AlertmanagerConfigStatus) {
+				status.SyncStatus = fmt.Sprintf("unable to set Alertmanager configuration: %v", err)
+			}); err != nil {
+				log.Error(err, "unable to set status")
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{}, err
+		}
+
+		active := metav1.Condition{
+			Type:   monitoringv1.ConditionActive,
+			Status: metav1.ConditionTrue,
+			Reason: "Synced",
+		}
+		if r.Cortex.DryRun() {
+			active.Reason = "DryRun"
+			active.Message = "The configuration was not written to Cortex in dry-run mode"
+		}
+		if err := r.patchStatus(ctx, config, func(status *monitoringv1.AlertmanagerConfigStatus) {
+			status.SyncStatus = syncedStatus(r.Cortex)
+			if !upToDate && !r.Cortex.DryRun() {
+				now := metav1.Now()
+				status.LastSyncTime = &now
+			}
+			status.Tenant = r.Tenant
+			meta.SetStatusCondition(&status.Conditions, pausedCondition(false))
+			meta.SetStatusCondition(&status.Conditions, active)
+		}); err != nil {
+			log.Error(err, "unable to set status")
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// isUpToDate checks if the Alertmanager configuration of the tenant in Cortex is the desired one.
+func (r *AlertmanagerConfigReconciler) isUpToDate(log logr.Logger, desired cortex.AlertmanagerConfig) (bool, error) {
+	current, err := r.Cortex.GetAlertmanagerConfig(log)
+	if errors.Is(err, cortex.ErrResourceNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return current.Equal(desired), nil
+}
+
+// activeConfig returns the AlertmanagerConfig written to Cortex: the oldest one that is not being deleted.
+// It returns nil if there is none.
+func (r *AlertmanagerConfigReconciler) activeConfig(ctx context.Context) (*monitoringv1.AlertmanagerConfig, error) {
+	var list monitoringv1.AlertmanagerConfigList
+	if err := r.List(ctx, &list); err != nil {
+		return nil, err
+	}
+
+	var candidates []monitoringv1.AlertmanagerConfig
+	for _, c := range list.Items {
+		if c.ObjectMeta.DeletionTimestamp.IsZero() {
+			candidates = append(candidates, c)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+			return a.CreationTimestamp.Before(&b.CreationTimestamp)
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	return &candidates[0], nil
+}
+
+// isPaused checks if writes to Cortex are paused for the current AlertmanagerConfig, either by annotation or operator-wide.
+func (r *AlertmanagerConfigReconciler) isPaused(config monitoringv1.AlertmanagerConfig) bool {
+	if r.Paused {
+		return true
+	}
+	paused, _ := strconv.ParseBool(config.Annotations[monitoringv1.PausedAnnotation])
+	return paused
+}
+
+// patchStatus applies mutate to the status of the current AlertmanagerConfig.
+func (r *AlertmanagerConfigReconciler) patchStatus(ctx context.Context, config monitoringv1.AlertmanagerConfig, mutate func(*monitoringv1.AlertmanagerConfigStatus)) error {
+	newConfig := config.DeepCopy()
+	mutate(&newConfig.Status)
+	return r.Status().Patch(ctx, newConfig, client.MergeFrom(&config))
+}
+
+// removeFinalizer removes our finalizer from the current AlertmanagerConfig.
+func (r *AlertmanagerConfigReconciler) removeFinalizer(ctx context.Context, config monitoringv1.AlertmanagerConfig, log logr.Logger) error {
+	log.Info("Removing finalizer")
+
+	newConfig := config.DeepCopy()
+	newConfig.ObjectMeta.Finalizers = removeString(config.ObjectMeta.Finalizers, alertmanagerConfigFinalizerName)
+	return r.Patch(ctx, newConfig, client.MergeFrom(&config))
+}
+
+// addFinalizer patches the current AlertmanagerConfig, so that it contains our finalizer.
+func (r *AlertmanagerConfigReconciler) addFinalizer(ctx context.Context, config monitoringv1.AlertmanagerConfig, log logr.Logger) error {
+	log.Info("Adding finalizer")
+
+	newConfig := config.DeepCopy()
+	newConfig.ObjectMeta.Finalizers = append(newConfig.ObjectMeta.Finalizers, alertmanagerConfigFinalizerName)
+	return r.Patch(ctx, newConfig, client.MergeFrom(&config))
+}
+
+// requestsForAll enqueues every AlertmanagerConfig, so a conflicting one takes over when the active one goes away.
+func (r *AlertmanagerConfigReconciler) requestsForAll(client.Object) []reconcile.Request {
+	var list monitoringv1.AlertmanagerConfigList
+	if err := r.List(context.Background(), &list); err != nil {
+		r.Log.Error(err, "unable to list AlertmanagerConfigs")
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(list.Items))
+	for _, c := range list.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: c.Namespace, Name: c.Name}})
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+// Status updates do not reconcile the AlertmanagerConfig itself, only changes of its spec and annotations
+// such as the paused annotation do. Only deletions and conflicts reconcile all others,
+// as they may change which one is active.
+func (r *AlertmanagerConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	changesActive := predicate.Funcs{
+		CreateFunc: func(event.CreateEvent) bool { return false },
+		DeleteFunc: func(event.DeleteEvent) bool { return true },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			if e.ObjectOld.GetDeletionTimestamp().IsZero() != e.ObjectNew.GetDeletionTimestamp().IsZero() {
+				return true
+			}
+			oldConfig, okOld := e.ObjectOld.(*monitoringv1.AlertmanagerConfig)
+			newConfig, okNew := e.ObjectNew.(*monitoringv1.AlertmanagerConfig)
+			return okOld && okNew && (oldConfig.Status.SyncStatus == "conflict") != (newConfig.Status.SyncStatus == "conflict")
+		},
+		GenericFunc: func(event.GenericEvent) bool { return false },
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&monitoringv1.AlertmanagerConfig{},
+			builder.WithPredicates(predicate.Or(predicate.GenerationChangedPredicate{}, predicate.AnnotationChangedPredicate{}))).
+		Watches(&source.Kind{Type: &monitoringv1.AlertmanagerConfig{}}, handler.EnqueueRequestsFromMapFunc(r.requestsForAll),
+			builder.WithPredicates(changesActive)).
+		Complete(r)
+}
@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ghodss/yaml"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	monitoringv1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+	"github.com/bolindalabs/cortex-alert-operator/controllers/cortex"
+)
+
+var _ = Describe("AlertmanagerConfig Controller", func() {
+	const (
+		timeout  = time.Second * 10
+		interval = time.Millisecond * 250
+	)
+
+	Context("When creating an AlertmanagerConfig", func() {
+		It("Should write the configuration to Cortex once and delete it with the last AlertmanagerConfig", func() {
+			config := newAlertmanagerConfig("test-alertmanagerconfig", "default")
+			desired, err := cortex.NewAlertmanagerConfig(config.Spec)
+			Expect(err).NotTo(HaveOccurred())
+			payload, err := yaml.Marshal(desired)
+			Expect(err).NotTo(HaveOccurred())
+
+			var mu sync.Mutex
+			var stored []byte
+			server.RouteToHandler("GET", "/api/v1/alerts", func(w http.ResponseWriter, req *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				if stored == nil {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				_, _ = w.Write(stored)
+			})
+			server.RouteToHandler("POST", "/api/v1/alerts", ghttp.CombineHandlers(
+				ghttp.VerifyBody(payload),
+				func(w http.ResponseWriter, req *http.Request) {
+					mu.Lock()
+					defer mu.Unlock()
+					stored = payload
+				},
+				ghttp.RespondWith(http.StatusCreated, ""),
+			))
+			server.RouteToHandler("DELETE", "/api/v1/alerts", ghttp.RespondWith(http.StatusOK, ""))
+
+			ctx := context.Background()
+			Expect(k8sClient.Create(ctx, config)).Should(Succeed())
+			lookupKey := types.NamespacedName{Name: "test-alertmanagerconfig", Namespace: "default"}
+			Eventually(func() string {
+				var synced monitoringv1.AlertmanagerConfig
+				if err := k8sClient.Get(ctx, lookupKey, &synced); err != nil {
+					return ""
+				}
+				return synced.Status.SyncStatus
+			}, timeout, interval).Should(Equal("synced"))
+			Expect(countRequests("POST", "/api/v1/alerts")).To(Equal(1))
+
+			By("By not writing the unchanged configuration again")
+			Consistently(func() int {
+				return countRequests("POST", "/api/v1/alerts")
+			}, time.Second*2, interval).Should(Equal(1))
+
+			By("By creating a conflicting AlertmanagerConfig")
+			conflicting := newAlertmanagerConfig("test-alertmanagerconfig-conflicting", "default")
+			conflicting.Spec.Route = &apiextensionsv1.JSON{Raw: []byte(`{"receiver":"other"}`)}
+			Expect(k8sClient.Create(ctx, conflicting)).Should(Succeed())
+			conflictingKey := types.NamespacedName{Name: "test-alertmanagerconfig-conflicting", Namespace: "default"}
+			Eventually(func() string {
+				var c monitoringv1.AlertmanagerConfig
+				if err := k8sClient.Get(ctx, conflictingKey, &c); err != nil {
+					return ""
+				}
+				return c.Status.SyncStatus
+			}, timeout, interval).Should(Equal("conflict"))
+			Consistently(func() int {
+				return countRequests("POST", "/api/v1/alerts")
+			}, time.Second*2, interval).Should(Equal(1))
+
+			By("By deleting the conflicting AlertmanagerConfig")
+			Expect(k8sClient.Delete(ctx, conflicting)).Should(Succeed())
+			Eventually(func() bool {
+				var deleted monitoringv1.AlertmanagerConfig
+				return apierrors.IsNotFound(k8sClient.Get(ctx, conflictingKey, &deleted))
+			}, timeout, interval).Should(BeTrue(), "AlertmanagerConfig should be deleted")
+			Expect(countRequests("POST", "/api/v1/alerts")).To(Equal(1))
+			Expect(countRequests("DELETE", "/api/v1/alerts")).To(Equal(0))
+
+			By("By deleting the AlertmanagerConfig")
+			Expect(k8sClient.Delete(ctx, config)).Should(Succeed())
+			Eventually(func() bool {
+				var deleted monitoringv1.AlertmanagerConfig
+				return apierrors.IsNotFound(k8sClient.Get(ctx, lookupKey, &deleted))
+			}, timeout, interval).Should(BeTrue(), "AlertmanagerConfig should be deleted")
+			Expect(countRequests("DELETE", "/api/v1/alerts")).To(Equal(1))
+		})
+	})
+})
+
+func newAlertmanagerConfig(name, namespace string) *monitoringv1.AlertmanagerConfig {
+	return &monitoringv1.AlertmanagerConfig{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "monitoring.bolinda.digital/v1",
+			Kind:       "AlertmanagerConfig",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: monitoringv1.AlertmanagerConfigSpec{
+			Route:     &apiextensionsv1.JSON{Raw: []byte(`{"receiver":"default"}`)},
+			Receivers: []apiextensionsv1.JSON{{Raw: []byte(`{"name":"default"}`)}},
+		},
+	}
+}
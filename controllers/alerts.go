@@ -0,0 +1,33 @@
+package controllers
+
+import (
+	"sort"
+
+	monitoringv1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+	"github.com/bolindalabs/cortex-alert-operator/controllers/cortex"
+)
+
+// summarizeAlerts counts the active alerts of the alerting rules in groups,
+// the rule groups of a single Cortex namespace as reported by the ruler.
+func summarizeAlerts(groups []cortex.GroupHealth) *monitoringv1.AlertSummary {
+	summary := &monitoringv1.AlertSummary{}
+	firing := map[string]bool{}
+	for _, g := range groups {
+		for _, rule := range g.Rules {
+			for _, a := range rule.Alerts {
+				switch a.State {
+				case cortex.AlertStateFiring:
+					summary.Firing++
+					if !firing[rule.Name] {
+						firing[rule.Name] = true
+						summary.FiringAlerts = append(summary.FiringAlerts, rule.Name)
+					}
+				case cortex.AlertStatePending:
+					summary.Pending++
+				}
+			}
+		}
+	}
+	sort.Strings(summary.FiringAlerts)
+	return summary
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	monitoringv1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+)
+
+// ClusterPrometheusRuleReconciler reconciles a ClusterPrometheusRule object.
+// It syncs rule groups exactly like the PrometheusRuleReconciler it embeds,
+// except that no namespace matcher is enforced on the expressions.
+type ClusterPrometheusRuleReconciler struct {
+	PrometheusRuleReconciler
+}
+
+//+kubebuilder:rbac:groups=monitoring.bolinda.digital,resources=clusterprometheusrules,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=monitoring.bolinda.digital,resources=clusterprometheusrules/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=monitoring.bolinda.digital,resources=clusterprometheusrules/finalizers,verbs=update
+
+// Reconcile syncs the rule groups of a ClusterPrometheusRule to Cortex.
+func (r *ClusterPrometheusRuleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("clusterprometheusrule", req.Name)
+
+	var rule monitoringv1.ClusterPrometheusRule
+	if err := r.Get(ctx, req.NamespacedName, &rule); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			return ctrl.Result{}, nil
+		}
+
+		log.Error(err, "unable to fetch ClusterPrometheusRule")
+		return ctrl.Result{}, err
+	}
+
+	return r.reconcileRule(ctx, log, &rule)
+}
+
+// requestsForAll enqueues every ClusterPrometheusRule, so they are checked against a changed RulePolicy.
+func (r *ClusterPrometheusRuleReconciler) requestsForAll(client.Object) []reconcile.Request {
+	var list monitoringv1.ClusterPrometheusRuleList
+	if err := r.List(context.Background(), &list); err != nil {
+		r.Log.Error(err, "unable to list ClusterPrometheusRules")
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(list.Items))
+	for _, rule := range list.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: rule.Name}})
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ClusterPrometheusRuleReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&monitoringv1.ClusterPrometheusRule{}).
+		Watches(&source.Kind{Type: &monitoringv1.RulePolicy{}}, handler.EnqueueRequestsFromMapFunc(r.requestsForAll)).
+		Complete(r)
+}
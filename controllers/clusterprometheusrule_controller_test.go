@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	monitoringv1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+	"github.com/bolindalabs/cortex-alert-operator/controllers/cortex"
+)
+
+var _ = Describe("ClusterPrometheusRule Controller", func() {
+	const (
+		timeout  = time.Second * 10
+		interval = time.Millisecond * 250
+	)
+
+	Context("When creating a ClusterPrometheusRule", func() {
+		It("Should write its rule groups to the Cortex namespace of cluster-scoped rules", func() {
+			ruler := serveRules()
+			ctx := context.Background()
+			rule := &monitoringv1.ClusterPrometheusRule{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "monitoring.bolinda.digital/v1",
+					Kind:       "ClusterPrometheusRule",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-clusterprometheusrule",
+				},
+				Spec: newPrometheusRule("test-clusterprometheusrule", "").Spec,
+			}
+			Expect(k8sClient.Create(ctx, rule)).Should(Succeed())
+
+			Eventually(func() []cortex.RuleGroup {
+				return ruler.groups("_cluster--test-clusterprometheusrule")
+			}, timeout, interval).Should(HaveLen(1))
+			g := ruler.groups("_cluster--test-clusterprometheusrule")[0]
+			Expect(g.Name).To(Equal("./example.rules"))
+			Expect(g.Rules).To(HaveLen(1))
+			Expect(g.Rules[0].Alert).To(Equal("ExampleAlert"))
+			Expect(g.Rules[0].Expr).To(Equal("vector(1)"))
+
+			By("By deleting the ClusterPrometheusRule")
+			Expect(k8sClient.Delete(ctx, rule)).Should(Succeed())
+			Eventually(func() bool {
+				var deleted monitoringv1.ClusterPrometheusRule
+				return apierrors.IsNotFound(k8sClient.Get(ctx, types.NamespacedName{Name: "test-clusterprometheusrule"}, &deleted))
+			}, timeout, interval).Should(BeTrue(), "ClusterPrometheusRule should be deleted")
+			Expect(ruler.groups("_cluster--test-clusterprometheusrule")).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,154 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	monitoringv1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+	"github.com/bolindalabs/cortex-alert-operator/controllers/render"
+)
+
+// ConfigMapReconciler turns the Prometheus rule files in selected ConfigMaps into PrometheusRules
+// owned by the ConfigMap, which are synced to Cortex like any other PrometheusRule.
+type ConfigMapReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+
+	// Selector selects the ConfigMaps holding rule files.
+	Selector labels.Selector
+}
+
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+
+// Reconcile creates or updates the PrometheusRule of a selected ConfigMap and deletes it
+// when the ConfigMap is no longer selected. Deleted ConfigMaps take their PrometheusRule
+// with them through the owner reference.
+func (r *ConfigMapReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("configmap", req.NamespacedName)
+
+	var cm corev1.ConfigMap
+	if err := r.Get(ctx, req.NamespacedName, &cm); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			return ctrl.Result{}, nil
+		}
+
+		log.Error(err, "unable to fetch ConfigMap")
+		return ctrl.Result{}, err
+	}
+
+	desired := render.NewConfigMapRule(&cm)
+	var current monitoringv1.PrometheusRule
+	exists := true
+	if err := r.Get(ctx, client.ObjectKeyFromObject(&desired), &current); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			log.Error(err, "unable to fetch PrometheusRule")
+			return ctrl.Result{}, err
+		}
+		exists = false
+	}
+	if exists && !metav1.IsControlledBy(&current, &cm) {
+		// Retrying does not help, one of them has to be renamed first.
+		log.Info("PrometheusRule of the same name exists, not syncing")
+		r.Recorder.Eventf(&cm, corev1.EventTypeWarning, "NameConflict", "PrometheusRule %s exists and is not owned by the ConfigMap", current.Name)
+		return ctrl.Result{}, nil
+	}
+
+	if !r.Selector.Matches(labels.Set(cm.Labels)) || !cm.ObjectMeta.DeletionTimestamp.IsZero() {
+		if exists {
+			log.Info("deleting PrometheusRule of ConfigMap")
+			if err := r.Delete(ctx, &current); client.IgnoreNotFound(err) != nil {
+				log.Error(err, "unable to delete PrometheusRule")
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{}, nil
+	}
+
+	groups, err := render.ParseRuleFiles(cm.Data)
+	if err != nil {
+		// Retrying does not help, the ConfigMap has to be fixed first. Until then the rules stay unchanged.
+		log.Error(err, "unable to parse rule files")
+		r.Recorder.Event(&cm, corev1.EventTypeWarning, "InvalidRuleFile", truncate(err.Error(), maxEventMessageLength))
+		return ctrl.Result{}, nil
+	}
+	desired.Spec.Groups = groups
+
+	if !exists {
+		if err := ctrl.SetControllerReference(&cm, &desired, r.Scheme); err != nil {
+			log.Error(err, "unable to set owner reference")
+			return ctrl.Result{}, err
+		}
+		if err := r.Create(ctx, &desired); err != nil {
+			log.Error(err, "unable to create PrometheusRule")
+			r.Recorder.Eventf(&cm, corev1.EventTypeWarning, "SyncFailed", "unable to create PrometheusRule: %v", err)
+			return ctrl.Result{}, err
+		}
+		r.Recorder.Eventf(&cm, corev1.EventTypeNormal, "Created", "created PrometheusRule %s", desired.Name)
+		return ctrl.Result{}, nil
+	}
+
+	// Comparing the specs would fight the defaulting webhook.
+	if current.Annotations[monitoringv1.ConfigMapVersionAnnotation] == cm.ResourceVersion {
+		return ctrl.Result{}, nil
+	}
+
+	newRule := current.DeepCopy()
+	newRule.Annotations = desired.Annotations
+	newRule.Spec = desired.Spec
+	if err := r.Patch(ctx, newRule, client.MergeFrom(&current)); err != nil {
+		log.Error(err, "unable to update PrometheusRule")
+		r.Recorder.Eventf(&cm, corev1.EventTypeWarning, "SyncFailed", "unable to update PrometheusRule: %v", err)
+		return ctrl.Result{}, err
+	}
+	r.Recorder.Eventf(&cm, corev1.EventTypeNormal, "Updated", "updated PrometheusRule %s", desired.Name)
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+// Only selected ConfigMaps, and those that were selected before an update, are reconciled,
+// as well as the owners of deleted PrometheusRules, so they are recreated.
+func (r *ConfigMapReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	isSelected := func(obj client.Object) bool {
+		return r.Selector.Matches(labels.Set(obj.GetLabels()))
+	}
+	isRuleSource := predicate.Funcs{
+		CreateFunc:  func(e event.CreateEvent) bool { return isSelected(e.Object) },
+		DeleteFunc:  func(e event.DeleteEvent) bool { return isSelected(e.Object) },
+		UpdateFunc:  func(e event.UpdateEvent) bool { return isSelected(e.ObjectOld) || isSelected(e.ObjectNew) },
+		GenericFunc: func(e event.GenericEvent) bool { return isSelected(e.Object) },
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.ConfigMap{}, builder.WithPredicates(isRuleSource)).
+		Owns(&monitoringv1.PrometheusRule{}).
+		Complete(r)
+}
@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	monitoringv1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+	"github.com/bolindalabs/cortex-alert-operator/controllers/cortex"
+)
+
+var _ = Describe("ConfigMap Controller", func() {
+	const (
+		timeout  = time.Second * 10
+		interval = time.Millisecond * 250
+	)
+
+	Context("When creating a ConfigMap with rule files", func() {
+		It("Should sync its rule groups until it is no longer selected", func() {
+			ruler := serveRules()
+			ctx := context.Background()
+			cm := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-rules",
+					Namespace: "default",
+					Labels:    map[string]string{"role": "alert-rules"},
+				},
+				Data: map[string]string{
+					"example.rules.yaml": "groups:\n- name: example\n  rules:\n  - alert: ExampleAlert\n    expr: vector(1)\n",
+				},
+			}
+			Expect(k8sClient.Create(ctx, cm)).Should(Succeed())
+
+			Eventually(func() []cortex.RuleGroup {
+				return ruler.groups("default--configmap_test-rules")
+			}, timeout, interval).Should(HaveLen(1))
+			g := ruler.groups("default--configmap_test-rules")[0]
+			Expect(g.Name).To(Equal("example"))
+			Expect(g.Rules).To(HaveLen(1))
+			Expect(g.Rules[0].Alert).To(Equal("ExampleAlert"))
+
+			var generated monitoringv1.PrometheusRule
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "test-rules", Namespace: "default"}, &generated)).Should(Succeed())
+			Expect(metav1.IsControlledBy(&generated, cm)).To(BeTrue())
+
+			By("By removing the ConfigMap from the selection")
+			// The test environment runs no garbage collector, so only unselecting deletes the PrometheusRule.
+			cm.Labels = nil
+			Expect(k8sClient.Update(ctx, cm)).Should(Succeed())
+			Eventually(func() []cortex.RuleGroup {
+				return ruler.groups("default--configmap_test-rules")
+			}, timeout, interval).Should(BeEmpty())
+			Eventually(func() bool {
+				var deleted monitoringv1.PrometheusRule
+				return apierrors.IsNotFound(k8sClient.Get(ctx, types.NamespacedName{Name: "test-rules", Namespace: "default"}, &deleted))
+			}, timeout, interval).Should(BeTrue(), "PrometheusRule should be deleted")
+
+			Expect(k8sClient.Delete(ctx, cm)).Should(Succeed())
+		})
+	})
+})
@@ -0,0 +1,130 @@
+package cortex
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+
+	"github.com/ghodss/yaml"
+	"github.com/go-logr/logr"
+
+	v1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+)
+
+const (
+	alertmanagerAPIPath = "/api/v1/alerts"
+)
+
+// AlertmanagerConfig is the Alertmanager configuration of a tenant as understood by the Cortex Alertmanager API.
+type AlertmanagerConfig struct {
+	TemplateFiles      map[string]string `json:"template_files,omitempty"`
+	AlertmanagerConfig string            `json:"alertmanager_config"`
+}
+
+// alertmanagerConfigFile is the Alertmanager configuration file embedded in AlertmanagerConfig.
+type alertmanagerConfigFile struct {
+	Global       json.RawMessage   `json:"global,omitempty"`
+	Route        json.RawMessage   `json:"route,omitempty"`
+	Receivers    []json.RawMessage `json:"receivers,omitempty"`
+	InhibitRules []json.RawMessage `json:"inhibit_rules,omitempty"`
+	Templates    []string          `json:"templates,omitempty"`
+}
+
+// NewAlertmanagerConfig converts spec into the representation sent to Cortex.
+// The template files are referenced by name from the configuration.
+func NewAlertmanagerConfig(spec v1.AlertmanagerConfigSpec) (AlertmanagerConfig, error) {
+	file := alertmanagerConfigFile{}
+	if spec.Global != nil {
+		file.Global = spec.Global.Raw
+	}
+	if spec.Route != nil {
+		file.Route = spec.Route.Raw
+	}
+	for _, r := range spec.Receivers {
+		file.Receivers = append(file.Receivers, r.Raw)
+	}
+	for _, r := range spec.InhibitRules {
+		file.InhibitRules = append(file.InhibitRules, r.Raw)
+	}
+	for name := range spec.Templates {
+		file.Templates = append(file.Templates, name)
+	}
+	sort.Strings(file.Templates)
+
+	config, err := yaml.Marshal(file)
+	if err != nil {
+		return AlertmanagerConfig{}, err
+	}
+
+	return AlertmanagerConfig{
+		TemplateFiles:      spec.Templates,
+		AlertmanagerConfig: string(config),
+	}, nil
+}
+
+// Equal reports whether c and other configure Alertmanager the same way.
+func (c AlertmanagerConfig) Equal(other AlertmanagerConfig) bool {
+	if c.AlertmanagerConfig != other.AlertmanagerConfig || len(c.TemplateFiles) != len(other.TemplateFiles) {
+		return false
+	}
+	for name, template := range c.TemplateFiles {
+		if t, ok := other.TemplateFiles[name]; !ok || t != template {
+			return false
+		}
+	}
+	return true
+}
+
+// GetAlertmanagerConfig returns the Alertmanager configuration of the tenant.
+func (c *Client) GetAlertmanagerConfig(log logr.Logger) (*AlertmanagerConfig, error) {
+	res, err := c.doRequest(log, alertmanagerAPIPath, "GET", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var config AlertmanagerConfig
+	if err := yaml.Unmarshal(body, &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// SetAlertmanagerConfig creates or replaces the Alertmanager configuration of the tenant.
+func (c *Client) SetAlertmanagerConfig(log logr.Logger, config AlertmanagerConfig) error {
+	payload, err := yaml.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	if c.dryRun {
+		skipWrite(log, "set_alertmanager_config", "payload", string(payload))
+		return nil
+	}
+
+	res, err := c.doRequest(log, alertmanagerAPIPath, "POST", payload)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	return nil
+}
+
+// DeleteAlertmanagerConfig deletes the Alertmanager configuration of the tenant.
+func (c *Client) DeleteAlertmanagerConfig(log logr.Logger) error {
+	if c.dryRun {
+		skipWrite(log, "delete_alertmanager_config")
+		return nil
+	}
+
+	_, err := c.doRequest(log, alertmanagerAPIPath, "DELETE", nil)
+	return err
+}
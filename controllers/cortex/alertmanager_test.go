@@ -0,0 +1,67 @@
+package cortex
+
+import (
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	v1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+)
+
+var _ = Describe("Alertmanager API", func() {
+	log := logf.Log
+
+	It("Should post the configuration with its templates", func() {
+		server.AppendHandlers(ghttp.CombineHandlers(
+			ghttp.VerifyRequest("POST", "/api/v1/alerts"),
+			ghttp.VerifyHeaderKV("X-Scope-OrgID", "tenant-a"),
+			ghttp.VerifyBody([]byte(`alertmanager_config: |
+  receivers:
+  - name: default
+  route:
+    receiver: default
+  templates:
+  - default.tmpl
+template_files:
+  default.tmpl: '{{ define "title" }}alert{{ end }}'
+`)),
+			ghttp.RespondWith(http.StatusCreated, nil),
+		))
+
+		config, err := NewAlertmanagerConfig(v1.AlertmanagerConfigSpec{
+			Route:     &apiextensionsv1.JSON{Raw: []byte(`{"receiver":"default"}`)},
+			Receivers: []apiextensionsv1.JSON{{Raw: []byte(`{"name":"default"}`)}},
+			Templates: map[string]string{"default.tmpl": `{{ define "title" }}alert{{ end }}`},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client.SetAlertmanagerConfig(log, config)).To(Succeed())
+		Expect(server.ReceivedRequests()).To(HaveLen(1))
+	})
+
+	It("Should compare configurations including their templates", func() {
+		config := AlertmanagerConfig{
+			AlertmanagerConfig: "route:\n  receiver: default\n",
+			TemplateFiles:      map[string]string{"default.tmpl": "alert"},
+		}
+		Expect(config.Equal(config)).To(BeTrue())
+		Expect(config.Equal(AlertmanagerConfig{AlertmanagerConfig: config.AlertmanagerConfig})).To(BeFalse())
+		Expect(config.Equal(AlertmanagerConfig{
+			AlertmanagerConfig: config.AlertmanagerConfig,
+			TemplateFiles:      map[string]string{"default.tmpl": "changed"},
+		})).To(BeFalse())
+		Expect(AlertmanagerConfig{}.Equal(AlertmanagerConfig{TemplateFiles: map[string]string{}})).To(BeTrue())
+	})
+
+	It("Should delete the configuration of the tenant", func() {
+		server.AppendHandlers(ghttp.CombineHandlers(
+			ghttp.VerifyRequest("DELETE", "/api/v1/alerts"),
+			ghttp.RespondWith(http.StatusOK, nil),
+		))
+
+		Expect(client.DeleteAlertmanagerConfig(log)).To(Succeed())
+	})
+})
@@ -0,0 +1,25 @@
+package cortex
+
+import (
+	"time"
+)
+
+// Alert states reported by the ruler.
+const (
+	AlertStateFiring  = "firing"
+	AlertStatePending = "pending"
+)
+
+// Alert is an active alert as reported by the ruler.
+type Alert struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+	State       string            `json:"state"`
+	ActiveAt    *time.Time        `json:"activeAt,omitempty"`
+	Value       string            `json:"value"`
+}
+
+// Name returns the alertname label of a.
+func (a Alert) Name() string {
+	return a.Labels["alertname"]
+}
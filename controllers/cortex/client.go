@@ -9,12 +9,22 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"sync"
+	"time"
 
 	"github.com/go-logr/logr"
 )
 
 const (
 	rulerAPIPath = "/api/v1/rules"
+
+	// LokiRulerAPIPath is the path of the rules API of the Loki ruler, which otherwise behaves like the Cortex ruler.
+	LokiRulerAPIPath = "/loki/api/v1/rules"
+
+	// DefaultPrometheusHTTPPrefix is the prefix of the Prometheus compatible API in Cortex.
+	DefaultPrometheusHTTPPrefix = "/prometheus"
+	// DefaultAlertmanagerHTTPPrefix is the prefix of the Alertmanager API in Cortex.
+	DefaultAlertmanagerHTTPPrefix = "/alertmanager"
 )
 
 var (
@@ -28,6 +38,14 @@ type Config struct {
 	Address         string `yaml:"address"`
 	ID              string `yaml:"id"`
 	UseLegacyRoutes bool   `yaml:"use_legacy_routes"`
+	// DryRun turns all write requests into no-ops that are only logged and counted.
+	DryRun bool `yaml:"dry_run"`
+	// PrometheusHTTPPrefix is the prefix of the Prometheus compatible API. Defaults to DefaultPrometheusHTTPPrefix.
+	PrometheusHTTPPrefix string `yaml:"prometheus_http_prefix"`
+	// AlertmanagerHTTPPrefix is the prefix of the Alertmanager API. Defaults to DefaultAlertmanagerHTTPPrefix.
+	AlertmanagerHTTPPrefix string `yaml:"alertmanager_http_prefix"`
+	// RulerAPIPath is the path of the rules API. Defaults to the Cortex path, use LokiRulerAPIPath for Loki.
+	RulerAPIPath string `yaml:"ruler_api_path"`
 }
 
 type Client struct {
@@ -37,6 +55,18 @@ type Client struct {
 	id       string
 	endpoint *url.URL
 	apiPath  string
+	dryRun   bool
+
+	prometheusPath   string
+	alertmanagerPath string
+
+	limitsMu      sync.Mutex
+	limits        RulerLimits
+	limitsFetched time.Time
+
+	healthMu      sync.Mutex
+	health        []GroupHealth
+	healthFetched time.Time
 }
 
 func New(cfg Config) (*Client, error) {
@@ -47,22 +77,71 @@ func New(cfg Config) (*Client, error) {
 
 	client := http.Client{}
 
+	apiPath := cfg.RulerAPIPath
+	if apiPath == "" {
+		apiPath = rulerAPIPath
+	}
+
+	prometheusPath := cfg.PrometheusHTTPPrefix
+	if prometheusPath == "" {
+		prometheusPath = DefaultPrometheusHTTPPrefix
+	}
+
+	alertmanagerPath := cfg.AlertmanagerHTTPPrefix
+	if alertmanagerPath == "" {
+		alertmanagerPath = DefaultAlertmanagerHTTPPrefix
+	}
+
 	c := &Client{
 		key:      cfg.Key,
 		id:       cfg.ID,
 		endpoint: endpoint,
 		Client:   client,
-		apiPath:  rulerAPIPath,
+		apiPath:  apiPath,
+		dryRun:   cfg.DryRun,
+
+		prometheusPath:   prometheusPath,
+		alertmanagerPath: alertmanagerPath,
 	}
 	return c, nil
 }
 
+// ID returns the tenant the client reads and writes.
+func (c *Client) ID() string {
+	return c.id
+}
+
+// Address returns the address of Cortex the client sends its requests to.
+func (c *Client) Address() string {
+	return c.endpoint.String()
+}
+
+// DryRun reports whether write requests are skipped.
+func (c *Client) DryRun() bool {
+	return c.dryRun
+}
+
 func (c *Client) doRequest(log logr.Logger, path, method string, payload []byte) (*http.Response, error) {
 	req, err := buildRequest(path, method, *c.endpoint, payload)
 	if err != nil {
 		return nil, err
 	}
 
+	return c.send(log, req)
+}
+
+// doJSONRequest is doRequest for APIs that require the payload to be declared as JSON.
+func (c *Client) doJSONRequest(log logr.Logger, path, method string, payload []byte) (*http.Response, error) {
+	req, err := buildRequest(path, method, *c.endpoint, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	return c.send(log, req)
+}
+
+func (c *Client) send(log logr.Logger, req *http.Request) (*http.Response, error) {
 	if c.key != "" {
 		req.SetBasicAuth(c.id, c.key)
 	}
@@ -92,6 +171,12 @@ func (c *Client) doRequest(log logr.Logger, path, method string, payload []byte)
 	return resp, nil
 }
 
+// skipWrite logs and counts a write request that is not sent in dry-run mode.
+func skipWrite(log logr.Logger, operation string, keysAndValues ...interface{}) {
+	dryRunWrites.WithLabelValues(operation).Inc()
+	log.WithValues(keysAndValues...).Info("dry-run: skipping write to cortex api", "operation", operation)
+}
+
 // checkResponse checks the API response for errors
 func checkResponse(log logr.Logger, r *http.Response) error {
 	log.WithValues(
@@ -0,0 +1,99 @@
+package cortex
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// Rule health values reported by the ruler.
+const (
+	HealthOK      = "ok"
+	HealthErr     = "err"
+	HealthUnknown = "unknown"
+)
+
+// GroupHealth is the evaluation state of a rule group as reported by the ruler.
+type GroupHealth struct {
+	Name string `json:"name"`
+	// File is the Cortex namespace of the group.
+	File           string       `json:"file"`
+	Rules          []RuleHealth `json:"rules"`
+	Interval       float64      `json:"interval"`
+	LastEvaluation time.Time    `json:"lastEvaluation"`
+	EvaluationTime float64      `json:"evaluationTime"`
+}
+
+// IsSlow checks if the last evaluation of g took longer than its interval,
+// which makes the ruler skip evaluations.
+func (g GroupHealth) IsSlow() bool {
+	return g.Interval > 0 && g.EvaluationTime > g.Interval
+}
+
+// RuleHealth is the evaluation state of a rule as reported by the ruler.
+type RuleHealth struct {
+	Name           string    `json:"name"`
+	Type           string    `json:"type"`
+	Health         string    `json:"health"`
+	LastError      string    `json:"lastError,omitempty"`
+	LastEvaluation time.Time `json:"lastEvaluation"`
+	EvaluationTime float64   `json:"evaluationTime"`
+	// Alerts are the active alerts of an alerting rule.
+	Alerts []Alert `json:"alerts,omitempty"`
+}
+
+type rulesResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		Groups []GroupHealth `json:"groups"`
+	} `json:"data"`
+	Error string `json:"error"`
+}
+
+// GetRuleHealth returns the evaluation state of the rule groups in namespace
+// from the Prometheus compatible rules API of the ruler.
+// Groups that were not loaded by the ruler yet are missing.
+// The API reports the groups of all namespaces of the tenant at once, so the response
+// is reused for all namespaces until it is older than maxAge.
+func (c *Client) GetRuleHealth(log logr.Logger, namespace string, maxAge time.Duration) ([]GroupHealth, error) {
+	c.healthMu.Lock()
+	defer c.healthMu.Unlock()
+
+	if c.healthFetched.IsZero() || time.Since(c.healthFetched) >= maxAge {
+		groups, err := c.listRuleHealth(log)
+		if err != nil {
+			return nil, err
+		}
+		c.health = groups
+		c.healthFetched = time.Now()
+	}
+
+	var groups []GroupHealth
+	for _, g := range c.health {
+		if g.File == namespace {
+			groups = append(groups, g)
+		}
+	}
+	return groups, nil
+}
+
+// listRuleHealth returns the evaluation state of all rule groups of the tenant.
+func (c *Client) listRuleHealth(log logr.Logger) ([]GroupHealth, error) {
+	res, err := c.doRequest(log, c.prometheusPath+"/api/v1/rules", "GET", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	var rules rulesResponse
+	if err := json.NewDecoder(res.Body).Decode(&rules); err != nil {
+		return nil, err
+	}
+	if rules.Status != "success" {
+		return nil, fmt.Errorf("rules request failed with status %q: %s", rules.Status, rules.Error)
+	}
+	return rules.Data.Groups, nil
+}
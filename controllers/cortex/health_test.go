@@ -0,0 +1,44 @@
+package cortex
+
+import (
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var _ = Describe("Rule health API", func() {
+	log := logf.Log
+
+	It("Should return the groups of the namespace", func() {
+		server.AppendHandlers(ghttp.CombineHandlers(
+			ghttp.VerifyRequest("GET", "/prometheus/api/v1/rules"),
+			ghttp.VerifyHeaderKV("X-Scope-OrgID", "tenant-a"),
+			ghttp.RespondWith(http.StatusOK, `{"status":"success","data":{"groups":[
+{"name":"example.rules","file":"team-a--example","interval":60,"evaluationTime":61.5,"lastEvaluation":"2021-03-01T10:00:00Z",
+ "rules":[{"name":"ExampleAlert","type":"alerting","health":"err","lastError":"many-to-many matching not allowed","evaluationTime":0.1,
+  "alerts":[{"labels":{"alertname":"ExampleAlert","severity":"page"},"annotations":{},"state":"firing","activeAt":"2021-03-01T10:00:00Z","value":"1e+00"}]}]},
+{"name":"other.rules","file":"team-b--other","interval":60,"rules":[]}]}}`),
+		))
+
+		groups, err := client.GetRuleHealth(log, "team-a--example", time.Minute)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(groups).To(HaveLen(1))
+		Expect(groups[0].Name).To(Equal("example.rules"))
+		Expect(groups[0].IsSlow()).To(BeTrue())
+		Expect(groups[0].Rules[0].Health).To(Equal(HealthErr))
+		Expect(groups[0].Rules[0].LastError).To(Equal("many-to-many matching not allowed"))
+		Expect(groups[0].Rules[0].Alerts).To(HaveLen(1))
+		Expect(groups[0].Rules[0].Alerts[0].Name()).To(Equal("ExampleAlert"))
+		Expect(groups[0].Rules[0].Alerts[0].State).To(Equal(AlertStateFiring))
+
+		By("Reusing the response for other namespaces of the tenant")
+		groups, err = client.GetRuleHealth(log, "team-b--other", time.Minute)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(groups).To(HaveLen(1))
+		Expect(server.ReceivedRequests()).To(HaveLen(1))
+	})
+})
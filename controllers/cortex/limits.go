@@ -0,0 +1,51 @@
+package cortex
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+const (
+	userLimitsPath = "/api/v1/user_limits"
+
+	// rulerLimitsTTL is how long the ruler limits read from Cortex are reused.
+	rulerLimitsTTL = 10 * time.Minute
+)
+
+// RulerLimits are the limits the ruler enforces for a tenant. Zero values are unlimited or unknown.
+type RulerLimits struct {
+	MaxRulesPerRuleGroup   int `json:"ruler_max_rules_per_rule_group"`
+	MaxRuleGroupsPerTenant int `json:"ruler_max_rule_groups_per_tenant"`
+}
+
+// GetRulerLimits returns the ruler limits of the tenant as reported by the user limits API.
+// Zero limits are returned if the API is not served or does not report ruler limits.
+// The limits are cached for rulerLimitsTTL.
+func (c *Client) GetRulerLimits(log logr.Logger) (RulerLimits, error) {
+	c.limitsMu.Lock()
+	defer c.limitsMu.Unlock()
+
+	if !c.limitsFetched.IsZero() && time.Since(c.limitsFetched) < rulerLimitsTTL {
+		return c.limits, nil
+	}
+
+	var limits RulerLimits
+	res, err := c.doRequest(log, userLimitsPath, "GET", nil)
+	switch {
+	case errors.Is(err, ErrResourceNotFound):
+	case err != nil:
+		return RulerLimits{}, err
+	default:
+		defer res.Body.Close()
+		if err := json.NewDecoder(res.Body).Decode(&limits); err != nil {
+			return RulerLimits{}, err
+		}
+	}
+
+	c.limits = limits
+	c.limitsFetched = time.Now()
+	return limits, nil
+}
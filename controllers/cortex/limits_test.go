@@ -0,0 +1,39 @@
+package cortex
+
+import (
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var _ = Describe("User limits API", func() {
+	log := logf.Log
+
+	It("Should read and cache the ruler limits of the tenant", func() {
+		server.AppendHandlers(ghttp.CombineHandlers(
+			ghttp.VerifyRequest("GET", "/api/v1/user_limits"),
+			ghttp.VerifyHeaderKV("X-Scope-OrgID", "tenant-a"),
+			ghttp.RespondWith(http.StatusOK, `{"ingestion_rate":25000,"ruler_max_rules_per_rule_group":20,"ruler_max_rule_groups_per_tenant":70}`),
+		))
+
+		limits, err := client.GetRulerLimits(log)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(limits).To(Equal(RulerLimits{MaxRulesPerRuleGroup: 20, MaxRuleGroupsPerTenant: 70}))
+
+		limits, err = client.GetRulerLimits(log)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(limits.MaxRulesPerRuleGroup).To(Equal(20))
+		Expect(server.ReceivedRequests()).To(HaveLen(1))
+	})
+
+	It("Should return no limits if the API is not served", func() {
+		server.AppendHandlers(ghttp.RespondWith(http.StatusNotFound, "404 page not found"))
+
+		limits, err := client.GetRulerLimits(log)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(limits).To(Equal(RulerLimits{}))
+	})
+})
@@ -0,0 +1,18 @@
+package cortex
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var dryRunWrites = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "cortex_alert_operator_dry_run_writes_total",
+		Help: "Number of write requests to Cortex skipped in dry-run mode.",
+	},
+	[]string{"operation"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(dryRunWrites)
+}
@@ -1,20 +1,140 @@
 package cortex
 
 import (
+	"bytes"
+	"errors"
+	"io/ioutil"
 	"net/url"
+	"strconv"
 
 	"github.com/ghodss/yaml"
 	"github.com/go-logr/logr"
+	"github.com/pmezard/go-difflib/difflib"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
 	v1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
 )
 
+// RuleGroup is a rule group as understood by the Cortex ruler API.
+type RuleGroup struct {
+	Name                          string   `json:"name"`
+	Interval                      string   `json:"interval,omitempty"`
+	Limit                         int      `json:"limit,omitempty"`
+	QueryOffset                   string   `json:"query_offset,omitempty"`
+	EvaluationDelay               string   `json:"evaluation_delay,omitempty"`
+	SourceTenants                 []string `json:"source_tenants,omitempty"`
+	AlignEvaluationTimeOnInterval bool     `json:"align_evaluation_time_on_interval,omitempty"`
+	Rules                         []Rule   `json:"rules"`
+}
+
+// Rule is an alerting or recording rule as understood by the Cortex ruler API.
+type Rule struct {
+	Record        string            `json:"record,omitempty"`
+	Alert         string            `json:"alert,omitempty"`
+	Expr          string            `json:"expr"`
+	For           string            `json:"for,omitempty"`
+	KeepFiringFor string            `json:"keep_firing_for,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// RuleNamespace is a Cortex namespace with its rule groups, in the rule file format of cortextool.
+type RuleNamespace struct {
+	Namespace string      `json:"namespace"`
+	Groups    []RuleGroup `json:"groups"`
+}
+
+// RuleFile is a rule file in the format of Prometheus, as read by promtool.
+type RuleFile struct {
+	Groups []RuleGroup `json:"groups"`
+}
+
+// NewRuleGroup converts group into the representation sent to Cortex.
+func NewRuleGroup(group v1.RuleGroup) RuleGroup {
+	g := RuleGroup{
+		Name:                          group.Name,
+		Interval:                      group.Interval,
+		Limit:                         group.Limit,
+		QueryOffset:                   group.QueryOffset,
+		EvaluationDelay:               group.EvaluationDelay,
+		SourceTenants:                 group.SourceTenants,
+		AlignEvaluationTimeOnInterval: group.AlignEvaluationTimeOnInterval,
+		Rules:                         make([]Rule, 0, len(group.Rules)),
+	}
+
+	for _, r := range group.Rules {
+		expr := r.Expr.StrVal
+		if r.Expr.Type == intstr.Int {
+			expr = strconv.Itoa(int(r.Expr.IntVal))
+		}
+
+		g.Rules = append(g.Rules, Rule{
+			Record:        r.Record,
+			Alert:         r.Alert,
+			Expr:          expr,
+			For:           r.For,
+			KeepFiringFor: r.KeepFiringFor,
+			Labels:        r.Labels,
+			Annotations:   r.Annotations,
+		})
+	}
+	return g
+}
+
+// Equal reports whether g and other are written to Cortex the same way.
+func (g RuleGroup) Equal(other RuleGroup) bool {
+	a, err := yaml.Marshal(g)
+	if err != nil {
+		return false
+	}
+	b, err := yaml.Marshal(other)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(a, b)
+}
+
+// ToV1 converts g into the representation used by PrometheusRules.
+func (g RuleGroup) ToV1() v1.RuleGroup {
+	group := v1.RuleGroup{
+		Name:                          g.Name,
+		Interval:                      g.Interval,
+		Limit:                         g.Limit,
+		QueryOffset:                   g.QueryOffset,
+		EvaluationDelay:               g.EvaluationDelay,
+		SourceTenants:                 g.SourceTenants,
+		AlignEvaluationTimeOnInterval: g.AlignEvaluationTimeOnInterval,
+		Rules:                         make([]v1.Rule, 0, len(g.Rules)),
+	}
+
+	for _, r := range g.Rules {
+		group.Rules = append(group.Rules, v1.Rule{
+			Record:        r.Record,
+			Alert:         r.Alert,
+			Expr:          intstr.FromString(r.Expr),
+			For:           r.For,
+			KeepFiringFor: r.KeepFiringFor,
+			Labels:        r.Labels,
+			Annotations:   r.Annotations,
+		})
+	}
+	return group
+}
+
+// SetRuleGroup creates or replaces group in namespace.
+// Federated groups are written with the tenant of the client as well; the ruler
+// evaluates them against the tenants listed in source_tenants.
 func (c *Client) SetRuleGroup(log logr.Logger, namespace string, group v1.RuleGroup) error {
-	payload, err := yaml.Marshal(&group)
+	payload, err := yaml.Marshal(NewRuleGroup(group))
 	if err != nil {
 		return err
 	}
 
+	if c.dryRun {
+		skipWrite(log, "set_rule_group", "namespace", namespace, "group", group.Name, "payload", string(payload))
+		return nil
+	}
+
 	escapedNamespace := url.PathEscape(namespace)
 	path := c.apiPath + "/" + escapedNamespace
 
@@ -28,7 +148,115 @@ func (c *Client) SetRuleGroup(log logr.Logger, namespace string, group v1.RuleGr
 	return nil
 }
 
+// ListRules returns the rule groups of all namespaces, keyed by namespace.
+func (c *Client) ListRules(log logr.Logger) (map[string][]RuleGroup, error) {
+	res, err := c.doRequest(log, c.apiPath, "GET", nil)
+	if errors.Is(err, ErrResourceNotFound) {
+		// Cortex responds with 404 if the tenant has no rule groups.
+		return map[string][]RuleGroup{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := map[string][]RuleGroup{}
+	if err := yaml.Unmarshal(body, &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// GetRuleNamespace returns the rule groups in namespace, or none if the namespace does not exist.
+func (c *Client) GetRuleNamespace(log logr.Logger, namespace string) ([]RuleGroup, error) {
+	res, err := c.doRequest(log, c.apiPath+"/"+url.PathEscape(namespace), "GET", nil)
+	if errors.Is(err, ErrResourceNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := map[string][]RuleGroup{}
+	if err := yaml.Unmarshal(body, &rules); err != nil {
+		return nil, err
+	}
+	return rules[namespace], nil
+}
+
+// GetRuleGroup returns the rule group groupName in namespace.
+func (c *Client) GetRuleGroup(log logr.Logger, namespace string, groupName string) (*RuleGroup, error) {
+	escapedNamespace := url.PathEscape(namespace)
+	escapedGroupName := url.PathEscape(groupName)
+	path := c.apiPath + "/" + escapedNamespace + "/" + escapedGroupName
+
+	res, err := c.doRequest(log, path, "GET", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var group RuleGroup
+	if err := yaml.Unmarshal(body, &group); err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// DiffRuleGroup returns a unified YAML diff from the rule group stored in Cortex to group.
+// The diff is empty if both are equal.
+func (c *Client) DiffRuleGroup(log logr.Logger, namespace string, group v1.RuleGroup) (string, error) {
+	desired, err := yaml.Marshal(NewRuleGroup(group))
+	if err != nil {
+		return "", err
+	}
+
+	var actual []byte
+	current, err := c.GetRuleGroup(log, namespace, group.Name)
+	switch {
+	case errors.Is(err, ErrResourceNotFound):
+	case err != nil:
+		return "", err
+	default:
+		if actual, err = yaml.Marshal(current); err != nil {
+			return "", err
+		}
+	}
+
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(actual)),
+		B:        difflib.SplitLines(string(desired)),
+		FromFile: "cortex/" + namespace + "/" + group.Name,
+		ToFile:   "desired/" + namespace + "/" + group.Name,
+		Context:  3,
+	})
+}
+
 func (c *Client) DeleteRuleGroup(log logr.Logger, namespace string, groupName string) error {
+	if c.dryRun {
+		skipWrite(log, "delete_rule_group", "namespace", namespace, "group", groupName)
+		return nil
+	}
+
 	escapedNamespace := url.PathEscape(namespace)
 	escapedGroupName := url.PathEscape(groupName)
 	path := c.apiPath + "/" + escapedNamespace + "/" + escapedGroupName
@@ -38,6 +266,11 @@ func (c *Client) DeleteRuleGroup(log logr.Logger, namespace string, groupName st
 }
 
 func (c *Client) DeleteRuleNamespace(log logr.Logger, namespace string) error {
+	if c.dryRun {
+		skipWrite(log, "delete_rule_namespace", "namespace", namespace)
+		return nil
+	}
+
 	escapedNamespace := url.PathEscape(namespace)
 	path := c.apiPath + "/" + escapedNamespace
 
@@ -0,0 +1,163 @@
+package cortex
+
+import (
+	"net/http"
+
+	"github.com/ghodss/yaml"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	v1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+)
+
+var _ = Describe("Rules API", func() {
+	log := logf.Log
+
+	Context("When setting a rule group", func() {
+		It("Should post the group in the ruler format", func() {
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("POST", "/api/v1/rules/team-a--example"),
+				ghttp.VerifyHeaderKV("X-Scope-OrgID", "tenant-a"),
+				ghttp.VerifyBody([]byte(`align_evaluation_time_on_interval: true
+limit: 10
+name: example.rules
+query_offset: 1m
+rules:
+- alert: ExampleAlert
+  expr: vector(1)
+  keep_firing_for: 5m
+- expr: "1"
+  record: one
+source_tenants:
+- tenant-b
+`)),
+				ghttp.RespondWith(http.StatusAccepted, nil),
+			))
+
+			err := client.SetRuleGroup(log, "team-a--example", v1.RuleGroup{
+				Name:                          "example.rules",
+				Limit:                         10,
+				QueryOffset:                   "1m",
+				SourceTenants:                 []string{"tenant-b"},
+				AlignEvaluationTimeOnInterval: true,
+				Rules: []v1.Rule{
+					{Alert: "ExampleAlert", Expr: intstr.FromString("vector(1)"), KeepFiringFor: "5m"},
+					{Record: "one", Expr: intstr.FromInt(1)},
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(server.ReceivedRequests()).To(HaveLen(1))
+		})
+	})
+	Context("When diffing a rule group", func() {
+		It("Should render the changes against the group stored in Cortex", func() {
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", "/api/v1/rules/team-a--example/example.rules"),
+				ghttp.RespondWith(http.StatusOK, `name: example.rules
+rules:
+- alert: ExampleAlert
+  expr: vector(0)
+`),
+			))
+
+			diff, err := client.DiffRuleGroup(log, "team-a--example", v1.RuleGroup{
+				Name:  "example.rules",
+				Rules: []v1.Rule{{Alert: "ExampleAlert", Expr: intstr.FromString("vector(1)")}},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(diff).To(ContainSubstring("-  expr: vector(0)\n+  expr: vector(1)\n"))
+		})
+	})
+
+	Context("When dry-run is enabled", func() {
+		It("Should not send write requests", func() {
+			dryRunClient, err := New(Config{Address: server.URL(), DryRun: true})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(dryRunClient.SetRuleGroup(log, "team-a--example", v1.RuleGroup{Name: "example.rules"})).To(Succeed())
+			Expect(dryRunClient.DeleteRuleGroup(log, "team-a--example", "example.rules")).To(Succeed())
+			Expect(dryRunClient.DeleteRuleNamespace(log, "team-a--example")).To(Succeed())
+			Expect(server.ReceivedRequests()).To(BeEmpty())
+		})
+	})
+	Context("When listing rules", func() {
+		It("Should return the rule groups of all namespaces", func() {
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", "/api/v1/rules"),
+				ghttp.RespondWith(http.StatusOK, `team-a--example:
+- name: example.rules
+  rules:
+  - alert: ExampleAlert
+    expr: vector(1)
+`),
+			))
+
+			rules, err := client.ListRules(log)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rules).To(HaveKey("team-a--example"))
+			Expect(rules["team-a--example"][0].ToV1().Rules[0].Expr).To(Equal(intstr.FromString("vector(1)")))
+		})
+
+		It("Should return the rule groups of a namespace", func() {
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", "/api/v1/rules/team-a--example"),
+				ghttp.RespondWith(http.StatusOK, `team-a--example:
+- name: example.rules-1
+  rules:
+  - alert: ExampleAlert
+    expr: vector(1)
+- name: example.rules-2
+  rules: []
+`),
+			))
+
+			groups, err := client.GetRuleNamespace(log, "team-a--example")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(groups).To(HaveLen(2))
+			Expect(groups[1].Name).To(Equal("example.rules-2"))
+		})
+
+		It("Should return no rule groups for a missing namespace", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusNotFound, "no rule groups found"))
+
+			groups, err := client.GetRuleNamespace(log, "team-a--example")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(groups).To(BeEmpty())
+		})
+
+		It("Should return no rule groups if the tenant has none", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusNotFound, "no rule groups found"))
+
+			rules, err := client.ListRules(log)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rules).To(BeEmpty())
+		})
+	})
+
+	Context("When comparing rule groups", func() {
+		It("Should match the group stored in Cortex to the one it was written from", func() {
+			var stored RuleGroup
+			Expect(yaml.Unmarshal([]byte(`name: example.rules
+rules:
+- alert: ExampleAlert
+  expr: 1
+`), &stored)).To(Succeed())
+
+			desired := v1.RuleGroup{
+				Name: "example.rules",
+				Rules: []v1.Rule{{
+					Alert:  "ExampleAlert",
+					Expr:   intstr.FromInt(1),
+					Labels: map[string]string{},
+				}},
+			}
+			Expect(stored.Equal(NewRuleGroup(desired))).To(BeTrue())
+
+			desired.Rules[0].For = "5m"
+			Expect(stored.Equal(NewRuleGroup(desired))).To(BeFalse())
+		})
+	})
+})
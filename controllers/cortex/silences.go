@@ -0,0 +1,143 @@
+package cortex
+
+import (
+	"encoding/json"
+	"net/url"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	v1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+)
+
+// Silence states reported by the Alertmanager.
+const (
+	SilenceStateActive  = "active"
+	SilenceStatePending = "pending"
+	SilenceStateExpired = "expired"
+)
+
+// Silence is a silence as understood by the Alertmanager v2 API.
+type Silence struct {
+	ID        string           `json:"id,omitempty"`
+	Matchers  []SilenceMatcher `json:"matchers"`
+	StartsAt  time.Time        `json:"startsAt"`
+	EndsAt    time.Time        `json:"endsAt"`
+	CreatedBy string           `json:"createdBy"`
+	Comment   string           `json:"comment"`
+	Status    *SilenceStatus   `json:"status,omitempty"`
+}
+
+// SilenceMatcher is a label matcher of a Silence.
+type SilenceMatcher struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	IsRegex bool   `json:"isRegex"`
+	IsEqual bool   `json:"isEqual"`
+}
+
+// SilenceStatus is the state of a Silence, as reported by the Alertmanager.
+type SilenceStatus struct {
+	State string `json:"state"`
+}
+
+// NewSilence converts spec into the representation sent to the Alertmanager.
+func NewSilence(spec v1.SilenceSpec) Silence {
+	s := Silence{
+		Matchers:  make([]SilenceMatcher, 0, len(spec.Matchers)),
+		StartsAt:  spec.StartsAt.UTC(),
+		EndsAt:    spec.EndsAt.UTC(),
+		CreatedBy: spec.CreatedBy,
+		Comment:   spec.Comment,
+	}
+
+	for _, m := range spec.Matchers {
+		isEqual := true
+		if m.IsEqual != nil {
+			isEqual = *m.IsEqual
+		}
+		s.Matchers = append(s.Matchers, SilenceMatcher{
+			Name:    m.Name,
+			Value:   m.Value,
+			IsRegex: m.IsRegex,
+			IsEqual: isEqual,
+		})
+	}
+	return s
+}
+
+// Equal checks if s and other silence the same alerts for the same time, ignoring ID and status.
+// The Alertmanager moves a start in the past to the time the silence is created, so start times
+// that both passed at now are considered equal.
+func (s Silence) Equal(other Silence, now time.Time) bool {
+	if len(s.Matchers) != len(other.Matchers) {
+		return false
+	}
+	for i := range s.Matchers {
+		if s.Matchers[i] != other.Matchers[i] {
+			return false
+		}
+	}
+	started := !s.StartsAt.After(now) && !other.StartsAt.After(now)
+	return (started || s.StartsAt.Equal(other.StartsAt)) && s.EndsAt.Equal(other.EndsAt) &&
+		s.CreatedBy == other.CreatedBy && s.Comment == other.Comment
+}
+
+type silenceResponse struct {
+	SilenceID string `json:"silenceID"`
+}
+
+// SetSilence creates silence, or updates it if it has an ID, and returns its ID.
+// The Alertmanager may assign a new ID on update, expiring the old silence.
+// In dry-run mode nothing is created, so the ID of silence is returned, if any.
+func (c *Client) SetSilence(log logr.Logger, silence Silence) (string, error) {
+	payload, err := json.Marshal(silence)
+	if err != nil {
+		return "", err
+	}
+
+	if c.dryRun {
+		skipWrite(log, "set_silence", "payload", string(payload))
+		return silence.ID, nil
+	}
+
+	res, err := c.doJSONRequest(log, c.alertmanagerPath+"/api/v2/silences", "POST", payload)
+	if err != nil {
+		return "", err
+	}
+
+	defer res.Body.Close()
+
+	var created silenceResponse
+	if err := json.NewDecoder(res.Body).Decode(&created); err != nil {
+		return "", err
+	}
+	return created.SilenceID, nil
+}
+
+// GetSilence returns the silence with id.
+func (c *Client) GetSilence(log logr.Logger, id string) (*Silence, error) {
+	res, err := c.doRequest(log, c.alertmanagerPath+"/api/v2/silence/"+url.PathEscape(id), "GET", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	var silence Silence
+	if err := json.NewDecoder(res.Body).Decode(&silence); err != nil {
+		return nil, err
+	}
+	return &silence, nil
+}
+
+// ExpireSilence expires the silence with id.
+func (c *Client) ExpireSilence(log logr.Logger, id string) error {
+	if c.dryRun {
+		skipWrite(log, "expire_silence", "id", id)
+		return nil
+	}
+
+	_, err := c.doRequest(log, c.alertmanagerPath+"/api/v2/silence/"+url.PathEscape(id), "DELETE", nil)
+	return err
+}
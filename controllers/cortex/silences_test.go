@@ -0,0 +1,72 @@
+package cortex
+
+import (
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	v1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+)
+
+var _ = Describe("Silences API", func() {
+	log := logf.Log
+	startsAt := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	It("Should create the silence and return its ID", func() {
+		isEqual := false
+		server.AppendHandlers(ghttp.CombineHandlers(
+			ghttp.VerifyRequest("POST", "/alertmanager/api/v2/silences"),
+			ghttp.VerifyHeaderKV("X-Scope-OrgID", "tenant-a"),
+			ghttp.VerifyContentType("application/json"),
+			ghttp.VerifyJSON(`{
+				"matchers": [
+					{"name": "alertname", "value": "ExampleAlert", "isRegex": false, "isEqual": true},
+					{"name": "env", "value": "dev.*", "isRegex": true, "isEqual": false}
+				],
+				"startsAt": "2021-03-01T10:00:00Z",
+				"endsAt": "2021-03-01T12:00:00Z",
+				"createdBy": "ops",
+				"comment": "maintenance"
+			}`),
+			ghttp.RespondWith(http.StatusOK, `{"silenceID":"4f5a"}`),
+		))
+
+		id, err := client.SetSilence(log, NewSilence(v1.SilenceSpec{
+			Matchers: []v1.SilenceMatcher{
+				{Name: "alertname", Value: "ExampleAlert"},
+				{Name: "env", Value: "dev.*", IsRegex: true, IsEqual: &isEqual},
+			},
+			StartsAt:  metav1.NewTime(startsAt),
+			EndsAt:    metav1.NewTime(startsAt.Add(2 * time.Hour)),
+			CreatedBy: "ops",
+			Comment:   "maintenance",
+		}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(id).To(Equal("4f5a"))
+	})
+
+	It("Should report silences that do not exist", func() {
+		server.AppendHandlers(ghttp.CombineHandlers(
+			ghttp.VerifyRequest("GET", "/alertmanager/api/v2/silence/4f5a"),
+			ghttp.RespondWith(http.StatusNotFound, nil),
+		))
+
+		_, err := client.GetSilence(log, "4f5a")
+		Expect(err).To(MatchError(ErrResourceNotFound))
+	})
+
+	It("Should ignore a start time the Alertmanager moved to the creation time", func() {
+		desired := Silence{StartsAt: startsAt, EndsAt: startsAt.Add(2 * time.Hour)}
+		current := desired
+		current.StartsAt = startsAt.Add(time.Minute)
+
+		Expect(desired.Equal(current, startsAt.Add(time.Hour))).To(BeTrue())
+		Expect(desired.Equal(current, startsAt.Add(30*time.Second))).To(BeFalse())
+		Expect(desired.Equal(current, startsAt.Add(-time.Hour))).To(BeFalse())
+	})
+})
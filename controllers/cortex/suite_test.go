@@ -0,0 +1,33 @@
+package cortex
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+func TestCortex(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Cortex Suite")
+}
+
+var server *ghttp.Server
+var client *Client
+
+// Start and Stop test server between each test run.
+var _ = BeforeEach(func() {
+	server = ghttp.NewServer()
+
+	var err error
+	client, err = New(Config{
+		Address: server.URL(),
+		ID:      "tenant-a",
+	})
+	Expect(err).ToNot(HaveOccurred())
+})
+
+var _ = AfterEach(func() {
+	server.Close()
+})
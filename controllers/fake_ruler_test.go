@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/ghodss/yaml"
+
+	"github.com/bolindalabs/cortex-alert-operator/controllers/cortex"
+)
+
+// rulesPath is the prefix of the rules API of the Cortex ruler.
+const rulesPath = "/api/v1/rules/"
+
+// fakeRuler serves the rules API of the Cortex ruler from memory, so specs can check the
+// rule groups the reconcilers wrote and change them behind the operator's back.
+type fakeRuler struct {
+	mu         sync.Mutex
+	namespaces map[string][]cortex.RuleGroup
+}
+
+// serveRules routes the rules API of the test server to a new fakeRuler.
+func serveRules() *fakeRuler {
+	f := &fakeRuler{namespaces: map[string][]cortex.RuleGroup{}}
+	for _, method := range []string{"GET", "POST", "DELETE"} {
+		server.RouteToHandler(method, regexp.MustCompile("^"+rulesPath), f.ServeHTTP)
+	}
+	return f
+}
+
+func (f *fakeRuler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	parts := strings.SplitN(strings.TrimPrefix(req.URL.Path, rulesPath), "/", 2)
+	namespace, group := parts[0], ""
+	if len(parts) == 2 {
+		group = parts[1]
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case req.Method == "POST":
+		var g cortex.RuleGroup
+		body, err := ioutil.ReadAll(req.Body)
+		if err == nil {
+			err = yaml.Unmarshal(body, &g)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.namespaces[namespace] = append(without(f.namespaces[namespace], g.Name), g)
+		w.WriteHeader(http.StatusAccepted)
+	case req.Method == "DELETE" && group == "":
+		delete(f.namespaces, namespace)
+		w.WriteHeader(http.StatusAccepted)
+	case req.Method == "DELETE":
+		if f.namespaces[namespace] = without(f.namespaces[namespace], group); len(f.namespaces[namespace]) == 0 {
+			delete(f.namespaces, namespace)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	case group != "":
+		for _, g := range f.namespaces[namespace] {
+			if g.Name == group {
+				respondYAML(w, g)
+				return
+			}
+		}
+		http.NotFound(w, req)
+	case len(f.namespaces[namespace]) == 0:
+		http.NotFound(w, req)
+	default:
+		respondYAML(w, map[string][]cortex.RuleGroup{namespace: f.namespaces[namespace]})
+	}
+}
+
+// groups returns the rule groups stored in namespace.
+func (f *fakeRuler) groups(namespace string) []cortex.RuleGroup {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]cortex.RuleGroup(nil), f.namespaces[namespace]...)
+}
+
+// set replaces the rule groups stored in namespace. Without groups the namespace is deleted.
+func (f *fakeRuler) set(namespace string, groups ...cortex.RuleGroup) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(groups) == 0 {
+		delete(f.namespaces, namespace)
+		return
+	}
+	f.namespaces[namespace] = groups
+}
+
+// countRequests counts the requests received by the test server with method and a path starting with prefix.
+func countRequests(method, prefix string) int {
+	var n int
+	for _, req := range server.ReceivedRequests() {
+		if req.Method == method && strings.HasPrefix(req.URL.Path, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+func without(groups []cortex.RuleGroup, name string) []cortex.RuleGroup {
+	var result []cortex.RuleGroup
+	for _, g := range groups {
+		if g.Name != name {
+			result = append(result, g)
+		}
+	}
+	return result
+}
+
+func respondYAML(w http.ResponseWriter, v interface{}) {
+	body, err := yaml.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(body)
+}
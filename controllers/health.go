@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	monitoringv1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+	"github.com/bolindalabs/cortex-alert-operator/controllers/cortex"
+)
+
+// checkHealth fetches the evaluation state and active alerts of the rule groups in cortexNamespace from the ruler
+// and records events for rules and groups that became unhealthy since the last check.
+func (r *PrometheusRuleReconciler) checkHealth(rule monitoringv1.RuleObject, log logr.Logger, ruler *cortex.Client, cortexNamespace string) (*monitoringv1.RuleHealthStatus, *monitoringv1.AlertSummary, error) {
+	groups, err := ruler.GetRuleHealth(log, cortexNamespace, r.HealthCheckInterval)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	health := newRuleHealthStatus(groups)
+
+	previous := rule.RuleStatus().Health
+	if previous == nil {
+		previous = &monitoringv1.RuleHealthStatus{}
+	}
+	for _, u := range health.UnhealthyRules {
+		if !containsUnhealthyRule(previous.UnhealthyRules, u) {
+			r.Recorder.Event(rule, corev1.EventTypeWarning, "RuleUnhealthy",
+				truncate(fmt.Sprintf("rule %s/%s failed to evaluate: %s", u.Group, u.Rule, u.LastError), maxEventMessageLength))
+		}
+	}
+	for _, g := range health.SlowGroups {
+		if !containsSlowGroup(previous.SlowGroups, g.Name) {
+			r.Recorder.Eventf(rule, corev1.EventTypeWarning, "SlowRuleGroup",
+				"rule group %s took %s to evaluate, longer than its interval of %s", g.Name, g.EvaluationTime, g.Interval)
+		}
+	}
+	return health, summarizeAlerts(groups), nil
+}
+
+// newRuleHealthStatus collects the failing rules and slow groups reported by the ruler.
+func newRuleHealthStatus(groups []cortex.GroupHealth) *monitoringv1.RuleHealthStatus {
+	health := &monitoringv1.RuleHealthStatus{LastChecked: metav1.Now()}
+	for _, g := range groups {
+		if g.IsSlow() {
+			health.SlowGroups = append(health.SlowGroups, monitoringv1.SlowGroup{
+				Name:           g.Name,
+				EvaluationTime: seconds(g.EvaluationTime).String(),
+				Interval:       seconds(g.Interval).String(),
+			})
+		}
+
+		for _, rule := range g.Rules {
+			if rule.Health != cortex.HealthErr {
+				continue
+			}
+
+			u := monitoringv1.UnhealthyRule{
+				Group:     g.Name,
+				Rule:      rule.Name,
+				LastError: rule.LastError,
+			}
+			if !rule.LastEvaluation.IsZero() {
+				lastEvaluation := metav1.NewTime(rule.LastEvaluation)
+				u.LastEvaluation = &lastEvaluation
+			}
+			health.UnhealthyRules = append(health.UnhealthyRules, u)
+		}
+	}
+	return health
+}
+
+func healthyCondition(health *monitoringv1.RuleHealthStatus) metav1.Condition {
+	switch {
+	case health == nil:
+		return metav1.Condition{
+			Type:    monitoringv1.ConditionHealthy,
+			Status:  metav1.ConditionUnknown,
+			Reason:  "HealthCheckFailed",
+			Message: "Unable to fetch the rule health from the Cortex ruler",
+		}
+	case len(health.UnhealthyRules) > 0:
+		return metav1.Condition{
+			Type:    monitoringv1.ConditionHealthy,
+			Status:  metav1.ConditionFalse,
+			Reason:  "EvaluationFailed",
+			Message: fmt.Sprintf("%d rules failed to evaluate", len(health.UnhealthyRules)),
+		}
+	case len(health.SlowGroups) > 0:
+		return metav1.Condition{
+			Type:    monitoringv1.ConditionHealthy,
+			Status:  metav1.ConditionFalse,
+			Reason:  "SlowEvaluation",
+			Message: fmt.Sprintf("%d rule groups take longer than their interval to evaluate", len(health.SlowGroups)),
+		}
+	}
+	return metav1.Condition{
+		Type:   monitoringv1.ConditionHealthy,
+		Status: metav1.ConditionTrue,
+		Reason: "EvaluationSucceeded",
+	}
+}
+
+func containsUnhealthyRule(rules []monitoringv1.UnhealthyRule, rule monitoringv1.UnhealthyRule) bool {
+	for _, r := range rules {
+		if r.Group == rule.Group && r.Rule == rule.Rule {
+			return true
+		}
+	}
+	return false
+}
+
+func containsSlowGroup(groups []monitoringv1.SlowGroup, name string) bool {
+	for _, g := range groups {
+		if g.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// seconds converts the floating point seconds used by the Prometheus API into a duration.
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second)).Round(time.Millisecond)
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	monitoringv1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+	"github.com/bolindalabs/cortex-alert-operator/controllers/policy"
+)
+
+//+kubebuilder:rbac:groups=monitoring.bolinda.digital,resources=rulepolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+
+// applicablePolicies returns the RulePolicies applying to rule.
+func applicablePolicies(ctx context.Context, c client.Reader, rule monitoringv1.RuleObject) ([]monitoringv1.RulePolicy, error) {
+	var policies monitoringv1.RulePolicyList
+	if err := c.List(ctx, &policies); err != nil {
+		return nil, err
+	}
+	if len(policies.Items) == 0 {
+		return nil, nil
+	}
+
+	var namespaceLabels map[string]string
+	if name := rule.GetNamespace(); name != "" {
+		var namespace corev1.Namespace
+		if err := c.Get(ctx, types.NamespacedName{Name: name}, &namespace); err != nil {
+			return nil, err
+		}
+		namespaceLabels = namespace.Labels
+	}
+
+	var applicable []monitoringv1.RulePolicy
+	for _, p := range policies.Items {
+		applies, err := policy.Applies(p, rule.GetNamespace(), namespaceLabels)
+		if err != nil {
+			return nil, err
+		}
+		if applies {
+			applicable = append(applicable, p)
+		}
+	}
+	return applicable, nil
+}
+
+// checkPolicies returns the rules of groups, the rendered groups of rule, that violate one of policies.
+func checkPolicies(rule monitoringv1.RuleObject, groups []monitoringv1.RuleGroup, policies []monitoringv1.RulePolicy) ([]monitoringv1.PolicyViolation, error) {
+	var violations []monitoringv1.PolicyViolation
+	for _, p := range policies {
+		v, err := policy.Check(p, groups, rule.RuleSpec().Backend)
+		if err != nil {
+			return nil, err
+		}
+		violations = append(violations, v...)
+	}
+	return violations, nil
+}
+
+// formatViolations lists violations one per line.
+func formatViolations(violations []monitoringv1.PolicyViolation) string {
+	lines := make([]string, 0, len(violations))
+	for _, v := range violations {
+		lines = append(lines, fmt.Sprintf("%s/%s: %s (policy %s)", v.Group, v.Rule, v.Message, v.Policy))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func policyCompliantCondition(violations []monitoringv1.PolicyViolation) metav1.Condition {
+	if len(violations) > 0 {
+		return metav1.Condition{
+			Type:    monitoringv1.ConditionPolicyCompliant,
+			Status:  metav1.ConditionFalse,
+			Reason:  "PolicyViolated",
+			Message: fmt.Sprintf("%d rule policy violations", len(violations)),
+		}
+	}
+	return metav1.Condition{
+		Type:   monitoringv1.ConditionPolicyCompliant,
+		Status: metav1.ConditionTrue,
+		Reason: "Compliant",
+	}
+}
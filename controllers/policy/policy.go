@@ -0,0 +1,149 @@
+// Package policy checks rules against RulePolicies.
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/promql/parser"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	v1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+)
+
+// severityLabel is the label AllowedSeverities applies to.
+const severityLabel = "severity"
+
+// Applies reports whether p applies to the rules in namespace, which has namespaceLabels.
+// An empty namespace stands for cluster-scoped rules, which only policies without namespace selector apply to.
+func Applies(p v1.RulePolicy, namespace string, namespaceLabels map[string]string) (bool, error) {
+	if p.Spec.NamespaceSelector == nil {
+		return true, nil
+	}
+	if namespace == "" {
+		return false, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(p.Spec.NamespaceSelector)
+	if err != nil {
+		return false, fmt.Errorf("invalid namespace selector of rule policy %s: %w", p.Name, err)
+	}
+	return selector.Matches(labels.Set(namespaceLabels)), nil
+}
+
+// Check returns the rules of groups that violate p. The expressions of the Loki backend
+// are not checked for forbidden functions.
+func Check(p v1.RulePolicy, groups []v1.RuleGroup, backend v1.Backend) ([]v1.PolicyViolation, error) {
+	var minFor time.Duration
+	if p.Spec.MinFor != "" {
+		d, err := model.ParseDuration(p.Spec.MinFor)
+		if err != nil {
+			return nil, fmt.Errorf("invalid minFor of rule policy %s: %w", p.Name, err)
+		}
+		minFor = time.Duration(d)
+	}
+
+	var violations []v1.PolicyViolation
+	for _, g := range groups {
+		for _, rule := range g.Rules {
+			var messages []string
+			if rule.Alert != "" {
+				messages = append(messages, checkAlert(p.Spec, rule, minFor)...)
+			}
+			if backend != v1.BackendLoki {
+				messages = append(messages, checkFunctions(p.Spec.ForbiddenFunctions, rule.Expr.String())...)
+			}
+
+			for _, message := range messages {
+				violations = append(violations, v1.PolicyViolation{
+					Policy:  p.Name,
+					Group:   g.Name,
+					Rule:    ruleName(rule),
+					Message: message,
+				})
+			}
+		}
+	}
+	return violations, nil
+}
+
+// checkAlert checks the labels, annotations and for duration of an alerting rule.
+func checkAlert(spec v1.RulePolicySpec, rule v1.Rule, minFor time.Duration) []string {
+	var messages []string
+	for _, name := range spec.RequiredLabels {
+		if rule.Labels[name] == "" {
+			messages = append(messages, fmt.Sprintf("missing required label %s", name))
+		}
+	}
+	for _, name := range spec.RequiredAnnotations {
+		if rule.Annotations[name] == "" {
+			messages = append(messages, fmt.Sprintf("missing required annotation %s", name))
+		}
+	}
+
+	if severity, ok := rule.Labels[severityLabel]; ok && len(spec.AllowedSeverities) > 0 && !contains(spec.AllowedSeverities, severity) {
+		messages = append(messages, fmt.Sprintf("severity %q is not one of %s", severity, strings.Join(spec.AllowedSeverities, ", ")))
+	}
+
+	if minFor > 0 {
+		var d model.Duration
+		if rule.For != "" {
+			var err error
+			if d, err = model.ParseDuration(rule.For); err != nil {
+				return append(messages, fmt.Sprintf("invalid for duration %q", rule.For))
+			}
+		}
+		if time.Duration(d) < minFor {
+			messages = append(messages, fmt.Sprintf("for duration %s is shorter than %s", d, model.Duration(minFor)))
+		}
+	}
+	return messages
+}
+
+// checkFunctions reports the forbidden functions called in expr.
+// Unparseable expressions are left to the ruler to reject.
+func checkFunctions(forbidden []string, expr string) []string {
+	if len(forbidden) == 0 {
+		return nil
+	}
+
+	e, err := parser.ParseExpr(expr)
+	if err != nil {
+		return nil
+	}
+
+	called := map[string]bool{}
+	parser.Inspect(e, func(node parser.Node, _ []parser.Node) error {
+		if call, ok := node.(*parser.Call); ok && contains(forbidden, call.Func.Name) {
+			called[call.Func.Name] = true
+		}
+		return nil
+	})
+
+	messages := make([]string, 0, len(called))
+	for name := range called {
+		messages = append(messages, fmt.Sprintf("function %s is forbidden", name))
+	}
+	sort.Strings(messages)
+	return messages
+}
+
+func ruleName(rule v1.Rule) string {
+	if rule.Alert != "" {
+		return rule.Alert
+	}
+	return rule.Record
+}
+
+func contains(slice []string, s string) bool {
+	for _, item := range slice {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
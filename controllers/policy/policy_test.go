@@ -0,0 +1,90 @@
+package policy
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	v1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+)
+
+func newPolicy() v1.RulePolicy {
+	return v1.RulePolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "alerting"},
+		Spec: v1.RulePolicySpec{
+			RequiredLabels:      []string{"severity", "team"},
+			RequiredAnnotations: []string{"runbook_url"},
+			AllowedSeverities:   []string{"page", "ticket"},
+			MinFor:              "5m",
+			ForbiddenFunctions:  []string{"holt_winters"},
+		},
+	}
+}
+
+var _ = Describe("Policy", func() {
+	It("Should report every violation of an alerting rule", func() {
+		groups := []v1.RuleGroup{{
+			Name: "example.rules",
+			Rules: []v1.Rule{
+				{
+					Alert:  "Noisy",
+					Expr:   intstr.FromString("holt_winters(up[1h], 0.5, 0.5) < 1"),
+					For:    "1m",
+					Labels: map[string]string{"severity": "critical"},
+				},
+				{
+					Alert:       "Fine",
+					Expr:        intstr.FromString("up == 0"),
+					For:         "10m",
+					Labels:      map[string]string{"severity": "page", "team": "a"},
+					Annotations: map[string]string{"runbook_url": "https://runbooks/fine"},
+				},
+				{
+					Record: "job:up:sum",
+					Expr:   intstr.FromString("sum by (job) (up)"),
+				},
+			},
+		}}
+
+		violations, err := Check(newPolicy(), groups, v1.BackendCortex)
+		Expect(err).NotTo(HaveOccurred())
+
+		var messages []string
+		for _, v := range violations {
+			Expect(v.Policy).To(Equal("alerting"))
+			Expect(v.Group).To(Equal("example.rules"))
+			Expect(v.Rule).To(Equal("Noisy"))
+			messages = append(messages, v.Message)
+		}
+		Expect(messages).To(Equal([]string{
+			"missing required label team",
+			"missing required annotation runbook_url",
+			`severity "critical" is not one of page, ticket`,
+			"for duration 1m is shorter than 5m",
+			"function holt_winters is forbidden",
+		}))
+	})
+
+	It("Should check forbidden functions of recording rules", func() {
+		groups := []v1.RuleGroup{{
+			Name:  "example.rules",
+			Rules: []v1.Rule{{Record: "job:up:forecast", Expr: intstr.FromString("holt_winters(up[1h], 0.5, 0.5)")}},
+		}}
+
+		violations, err := Check(newPolicy(), groups, v1.BackendCortex)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(violations).To(HaveLen(1))
+		Expect(violations[0].Rule).To(Equal("job:up:forecast"))
+	})
+
+	It("Should apply policies by namespace selector", func() {
+		p := newPolicy()
+		Expect(Applies(p, "", nil)).To(BeTrue())
+
+		p.Spec.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "prod"}}
+		Expect(Applies(p, "team-a", map[string]string{"tier": "prod"})).To(BeTrue())
+		Expect(Applies(p, "team-b", map[string]string{"tier": "dev"})).To(BeFalse())
+		Expect(Applies(p, "", nil)).To(BeFalse())
+	})
+})
@@ -0,0 +1,72 @@
+package policy
+
+import (
+	"fmt"
+
+	v1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+)
+
+// Usage counts the rule groups and rules in the scope of a quota.
+type Usage struct {
+	Groups int
+	Rules  int
+}
+
+// Add returns the sum of u and the rule groups and rules of groups.
+func (u Usage) Add(groups []v1.RuleGroup) Usage {
+	u.Groups += len(groups)
+	for _, g := range groups {
+		u.Rules += len(g.Rules)
+	}
+	return u
+}
+
+// EffectiveQuota combines base and the quotas of policies into their strictest limits.
+func EffectiveQuota(base v1.RuleQuota, policies []v1.RulePolicy) v1.RuleQuota {
+	q := base
+	for _, p := range policies {
+		if p.Spec.Quota != nil {
+			q = Stricter(q, *p.Spec.Quota)
+		}
+	}
+	return q
+}
+
+// Stricter returns the lower of each limit of a and b.
+func Stricter(a, b v1.RuleQuota) v1.RuleQuota {
+	return v1.RuleQuota{
+		MaxGroups:        stricter(a.MaxGroups, b.MaxGroups),
+		MaxRulesPerGroup: stricter(a.MaxRulesPerGroup, b.MaxRulesPerGroup),
+		MaxRules:         stricter(a.MaxRules, b.MaxRules),
+	}
+}
+
+// CheckQuota returns how groups exceed q, given the usage of the other rules in scope,
+// e.g. "namespace team-a". It returns nil if groups fit.
+func CheckQuota(q v1.RuleQuota, scope string, groups []v1.RuleGroup, others Usage) []string {
+	var exceeded []string
+	if q.MaxRulesPerGroup > 0 {
+		for _, g := range groups {
+			if len(g.Rules) > q.MaxRulesPerGroup {
+				exceeded = append(exceeded, fmt.Sprintf("rule group %s has %d rules, the limit is %d", g.Name, len(g.Rules), q.MaxRulesPerGroup))
+			}
+		}
+	}
+
+	total := others.Add(groups)
+	if q.MaxGroups > 0 && total.Groups > q.MaxGroups {
+		exceeded = append(exceeded, fmt.Sprintf("%s would have %d rule groups, the limit is %d", scope, total.Groups, q.MaxGroups))
+	}
+	if q.MaxRules > 0 && total.Rules > q.MaxRules {
+		exceeded = append(exceeded, fmt.Sprintf("%s would have %d rules, the limit is %d", scope, total.Rules, q.MaxRules))
+	}
+	return exceeded
+}
+
+// stricter returns the lower of two limits, where 0 is unlimited.
+func stricter(a, b int) int {
+	if a == 0 || (b > 0 && b < a) {
+		return b
+	}
+	return a
+}
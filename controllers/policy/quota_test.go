@@ -0,0 +1,47 @@
+package policy
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	v1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+)
+
+func newGroups(rules ...int) []v1.RuleGroup {
+	groups := make([]v1.RuleGroup, 0, len(rules))
+	for i, n := range rules {
+		g := v1.RuleGroup{Name: string(rune('a' + i))}
+		for j := 0; j < n; j++ {
+			g.Rules = append(g.Rules, v1.Rule{Record: "job:up:sum", Expr: intstr.FromString("sum by (job) (up)")})
+		}
+		groups = append(groups, g)
+	}
+	return groups
+}
+
+var _ = Describe("Quota", func() {
+	It("Should combine quotas into the strictest limits", func() {
+		q := EffectiveQuota(v1.RuleQuota{MaxGroups: 10, MaxRules: 100}, []v1.RulePolicy{
+			{Spec: v1.RulePolicySpec{Quota: &v1.RuleQuota{MaxGroups: 20, MaxRulesPerGroup: 5}}},
+			{Spec: v1.RulePolicySpec{Quota: &v1.RuleQuota{MaxRules: 50}}},
+			{},
+		})
+		Expect(q).To(Equal(v1.RuleQuota{MaxGroups: 10, MaxRulesPerGroup: 5, MaxRules: 50}))
+	})
+
+	It("Should count the usage of other rules", func() {
+		q := v1.RuleQuota{MaxGroups: 3, MaxRulesPerGroup: 2, MaxRules: 5}
+		Expect(CheckQuota(q, "namespace team-a", newGroups(2, 1), Usage{Groups: 1, Rules: 2})).To(BeEmpty())
+
+		Expect(CheckQuota(q, "namespace team-a", newGroups(3, 1), Usage{Groups: 2, Rules: 2})).To(Equal([]string{
+			"rule group a has 3 rules, the limit is 2",
+			"namespace team-a would have 4 rule groups, the limit is 3",
+			"namespace team-a would have 6 rules, the limit is 5",
+		}))
+	})
+
+	It("Should not limit zero quotas", func() {
+		Expect(CheckQuota(v1.RuleQuota{}, "tenant", newGroups(1000), Usage{Groups: 1000, Rules: 100000})).To(BeEmpty())
+	})
+})
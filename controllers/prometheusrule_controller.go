@@ -18,31 +18,78 @@ package controllers
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	monitoringv1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
 	"github.com/bolindalabs/cortex-alert-operator/controllers/cortex"
+	"github.com/bolindalabs/cortex-alert-operator/controllers/render"
+	"github.com/bolindalabs/cortex-alert-operator/controllers/ruletest"
 )
 
-const finalizerName = "prometheus.monitoring.bolinda.digital"
+const (
+	finalizerName = "prometheus.monitoring.bolinda.digital"
+
+	// maxEventMessageLength keeps event messages below the size the API server accepts.
+	maxEventMessageLength = 1024
+	// maxStatusDiffLength limits the diff stored in the status.
+	maxStatusDiffLength = 4096
+	// quotaRetryInterval is how often rules over quota are checked again.
+	quotaRetryInterval = 5 * time.Minute
+)
 
 // PrometheusRuleReconciler reconciles a PrometheusRule object
 type PrometheusRuleReconciler struct {
 	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
-
-	Cortex *cortex.Client
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+
+	Cortex   *cortex.Client
+	Renderer render.Renderer
+	// Loki is the client of the Loki ruler for PrometheusRules of the Loki backend. Optional.
+	Loki *cortex.Client
+
+	// Paused stops all writes to Cortex for every PrometheusRule.
+	Paused bool
+	// DeletionPolicy applies to PrometheusRules that do not set their own. Defaults to Delete.
+	DeletionPolicy monitoringv1.DeletionPolicy
+	// HealthCheckInterval, if set, is how often synced PrometheusRules are reconciled
+	// to refresh the rule health reported by the Cortex ruler. The ruler is asked at most once per interval and tenant.
+	HealthCheckInterval time.Duration
+	// Quota limits the rule groups and rules synced per namespace and tenant.
+	Quota QuotaLimits
+	// RulerLimits are the limits of the ruler. Unset limits are read from the user limits API of Cortex, if served.
+	RulerLimits cortex.RulerLimits
+	// SplitGroups splits rule groups with more rules than the ruler allows into numbered groups.
+	SplitGroups bool
+	// TestLimits bound the resources the unit tests of a PrometheusRule may use.
+	TestLimits ruletest.Limits
 }
 
 //+kubebuilder:rbac:groups=monitoring.bolinda.digital,resources=prometheusrules,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=monitoring.bolinda.digital,resources=prometheusrules/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=monitoring.bolinda.digital,resources=prometheusrules/finalizers,verbs=update
+//+kubebuilder:rbac:groups=monitoring.bolinda.digital,resources=ruletemplates,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -69,74 +116,419 @@ func (r *PrometheusRuleReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		return ctrl.Result{}, err
 	}
 
-	cortexNamespace := rule.Namespace + "--" + rule.Name
+	return r.reconcileRule(ctx, log, &rule)
+}
+
+// reconcileRule syncs the rule groups of a PrometheusRule or ClusterPrometheusRule to Cortex.
+func (r *PrometheusRuleReconciler) reconcileRule(ctx context.Context, log logr.Logger, rule monitoringv1.RuleObject) (ctrl.Result, error) {
+	cortexNamespace, namespaceErr := r.Renderer.CortexNamespace(rule)
+	deletionPolicy := r.deletionPolicy(rule)
+	ruler := r.ruler(rule)
 
 	switch {
-	case !r.hasFinalizer(rule) && !r.isDeletionScheduled(rule):
+	case deletionPolicy == monitoringv1.DeletionPolicyOrphan && r.hasFinalizer(rule):
+		if err := r.removeFinalizer(ctx, rule, log); err != nil {
+			log.Error(err, "unable to remove finalizer")
+			return ctrl.Result{}, err
+		}
+	case deletionPolicy != monitoringv1.DeletionPolicyOrphan && !r.hasFinalizer(rule) && !r.isDeletionScheduled(rule):
 		if err := r.addFinalizer(ctx, rule, log); err != nil {
 			log.Error(err, "unable to add finalizer")
 			return ctrl.Result{}, err
 		}
-	case r.isDeletionScheduled(rule):
-		if err := r.Cortex.DeleteRuleNamespace(log, cortexNamespace); err != nil {
-			log.Error(err, "unable to delete rule namespace")
+	case ruler == nil:
+		// Without a ruler for the backend nothing was ever written, so there is nothing to clean up either.
+		log.Info("no ruler configured for backend", "backend", rule.RuleSpec().Backend)
+		if r.isDeletionScheduled(rule) {
+			if r.hasFinalizer(rule) {
+				if err := r.removeFinalizer(ctx, rule, log); err != nil {
+					log.Error(err, "unable to remove finalizer")
+					return ctrl.Result{}, err
+				}
+			}
+		} else if err := r.setStatus(ctx, rule, fmt.Sprintf("no ruler configured for backend %s", rule.RuleSpec().Backend)); err != nil {
+			log.Error(err, "unable to set status")
 			return ctrl.Result{}, err
 		}
-		if err := r.removeFinalizer(ctx, rule, log); err != nil {
-			log.Error(err, "unable to remove finalizer")
+	case namespaceErr != nil:
+		// Nothing is ever written to a Cortex namespace the rule may not use, so there is nothing to clean up either.
+		log.Error(namespaceErr, "invalid Cortex namespace")
+		if r.isDeletionScheduled(rule) {
+			if r.hasFinalizer(rule) {
+				if err := r.removeFinalizer(ctx, rule, log); err != nil {
+					log.Error(err, "unable to remove finalizer")
+					return ctrl.Result{}, err
+				}
+			}
+		} else {
+			r.Recorder.Event(rule, corev1.EventTypeWarning, "InvalidCortexNamespace", namespaceErr.Error())
+			if err := r.setStatus(ctx, rule, fmt.Sprintf("invalid Cortex namespace: %v", namespaceErr)); err != nil {
+				log.Error(err, "unable to set status")
+				return ctrl.Result{}, err
+			}
+		}
+	case r.isPaused(rule):
+		log.Info("reconciliation paused, leaving Cortex untouched")
+		if err := r.setPaused(ctx, rule, true); err != nil {
+			log.Error(err, "unable to set status")
 			return ctrl.Result{}, err
 		}
+	case r.isDeletionScheduled(rule):
+		if deletionPolicy == monitoringv1.DeletionPolicyDelete {
+			if err := ruler.DeleteRuleNamespace(log, cortexNamespace); err != nil {
+				log.Error(err, "unable to delete rule namespace")
+				return ctrl.Result{}, err
+			}
+		} else {
+			log.Info("retaining rule namespace", "deletionPolicy", deletionPolicy)
+		}
+		if r.hasFinalizer(rule) {
+			if err := r.removeFinalizer(ctx, rule, log); err != nil {
+				log.Error(err, "unable to remove finalizer")
+				return ctrl.Result{}, err
+			}
+		}
 	default:
-		for _, g := range rule.Spec.Groups {
-			if err := r.Cortex.SetRuleGroup(log, cortexNamespace, g); err != nil {
-				log.Error(err, "unable to set rule group")
+		expanded, err := render.ExpandTemplates(rule, r.templateLookup(ctx, rule.GetNamespace()))
+		if err != nil {
+			log.Error(err, "unable to instantiate rule templates")
 
-				if err := r.setStatus(ctx, rule, fmt.Sprintf("unable to set rule group: %v", err)); err != nil {
-					log.Error(err, "unable to set rule group")
+			if err := r.setStatus(ctx, rule, fmt.Sprintf("unable to instantiate rule templates: %v", err)); err != nil {
+				log.Error(err, "unable to set status")
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{}, err
+		}
+
+		result, err := r.Renderer.Render(expanded, cortexNamespace)
+		if err != nil {
+			log.Error(err, "unable to render rule groups")
+
+			if err := r.setStatus(ctx, rule, fmt.Sprintf("unable to render rule groups: %v", err)); err != nil {
+				log.Error(err, "unable to set status")
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{}, err
+		}
+
+		limits := r.rulerLimits(log, ruler)
+		if r.SplitGroups {
+			if result.Groups, err = render.SplitGroups(result.Groups, limits.MaxRulesPerRuleGroup); err != nil {
+				log.Error(err, "unable to split rule groups")
+
+				if err := r.setStatus(ctx, rule, fmt.Sprintf("unable to split rule groups: %v", err)); err != nil {
+					log.Error(err, "unable to set status")
 					return ctrl.Result{}, err
 				}
 				return ctrl.Result{}, err
 			}
+		}
+
+		policies, err := applicablePolicies(ctx, r.Client, rule)
+		if err != nil {
+			log.Error(err, "unable to get rule policies")
 
-			if err := r.setStatus(ctx, rule, "synced"); err != nil {
+			if err := r.setStatus(ctx, rule, fmt.Sprintf("unable to get rule policies: %v", err)); err != nil {
 				log.Error(err, "unable to set status")
 				return ctrl.Result{}, err
 			}
+			return ctrl.Result{}, err
+		}
+
+		violations, err := checkPolicies(rule, result.Groups, policies)
+		if err != nil {
+			log.Error(err, "unable to check rule policies")
+
+			if err := r.setStatus(ctx, rule, fmt.Sprintf("unable to check rule policies: %v", err)); err != nil {
+				log.Error(err, "unable to set status")
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{}, err
+		}
+		if len(violations) > 0 {
+			// Like failing tests, violations keep the rules in Cortex unchanged until the PrometheusRule or the policy is fixed.
+			log.Info("rules violate rule policies, not syncing", "violations", len(violations))
+			r.Recorder.Event(rule, corev1.EventTypeWarning, "PolicyViolation", truncate(formatViolations(violations), maxEventMessageLength))
+			if err := r.patchStatus(ctx, rule, func(status *monitoringv1.PrometheusRuleStatus) {
+				status.SyncStatus = "rule policies violated"
+				status.PolicyViolations = violations
+				meta.SetStatusCondition(&status.Conditions, policyCompliantCondition(violations))
+			}); err != nil {
+				log.Error(err, "unable to set status")
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{}, nil
+		}
+
+		exceeded, err := checkQuota(ctx, r.Client, rule, result.Groups, policies, quotaLimits(r.Quota, limits))
+		if err != nil {
+			log.Error(err, "unable to check rule quotas")
+
+			if err := r.setStatus(ctx, rule, fmt.Sprintf("unable to check rule quotas: %v", err)); err != nil {
+				log.Error(err, "unable to set status")
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{}, err
+		}
+		if len(exceeded) > 0 {
+			// The quota may free up when other rules shrink, which does not trigger a reconciliation of this one.
+			log.Info("rules exceed quotas, not syncing", "exceeded", exceeded)
+			r.Recorder.Event(rule, corev1.EventTypeWarning, "QuotaExceeded", truncate(strings.Join(exceeded, "\n"), maxEventMessageLength))
+			if err := r.patchStatus(ctx, rule, func(status *monitoringv1.PrometheusRuleStatus) {
+				status.SyncStatus = "quota exceeded"
+				meta.SetStatusCondition(&status.Conditions, quotaExceededCondition(exceeded))
+			}); err != nil {
+				log.Error(err, "unable to set status")
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{RequeueAfter: quotaRetryInterval}, nil
+		}
+
+		// Rules that did not change since the last sync already passed their tests. The groups stored in Cortex are
+		// compared on every pass, so groups changed or deleted in Cortex directly are written again.
+		checksum, err := syncChecksum(ruler, rule.RuleSpec().Backend, cortexNamespace, result.Groups, rule.RuleSpec().Tests)
+		if err != nil {
+			log.Error(err, "unable to checksum rule groups")
+			return ctrl.Result{}, err
+		}
+		current, err := ruler.GetRuleNamespace(log, cortexNamespace)
+		if err != nil {
+			log.Error(err, "unable to get rule groups")
+
+			if err := r.setStatus(ctx, rule, fmt.Sprintf("unable to get rule groups: %v", err)); err != nil {
+				log.Error(err, "unable to set status")
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{}, err
+		}
+		last := rule.RuleStatus()
+		changed := last.SyncStatus != "synced" || last.SyncedChecksum != checksum
+		outdated := outdatedRuleGroups(current, result.Groups)
+		stale := staleRuleGroups(current, result.Groups)
+		upToDate := !changed && len(outdated) == 0 && len(stale) == 0
+
+		var diffs []string
+		if !upToDate {
+			if changed && len(rule.RuleSpec().Tests) > 0 {
+				failures := []string{"unit tests are not supported for the Loki backend"}
+				if rule.RuleSpec().Backend != monitoringv1.BackendLoki {
+					failures = ruletest.Run(result.Groups, rule.RuleSpec().Tests, r.TestLimits)
+				}
+				if err := r.patchStatus(ctx, rule, func(status *monitoringv1.PrometheusRuleStatus) {
+					status.TestFailures = failures
+					meta.SetStatusCondition(&status.Conditions, testsPassedCondition(failures))
+					if len(failures) > 0 {
+						status.SyncStatus = "rule tests failed"
+					}
+				}); err != nil {
+					log.Error(err, "unable to set status")
+					return ctrl.Result{}, err
+				}
+
+				if len(failures) > 0 {
+					// The rules stay unchanged in Cortex until the PrometheusRule is fixed, which triggers a new reconciliation.
+					log.Info("rule tests failed, not syncing", "failures", failures)
+					r.Recorder.Event(rule, corev1.EventTypeWarning, "TestsFailed", truncate(strings.Join(failures, "\n"), maxEventMessageLength))
+					return ctrl.Result{}, nil
+				}
+			}
+
+			for _, g := range outdated {
+				if ruler.DryRun() {
+					diffs = append(diffs, r.recordDryRun(rule, log, ruler, cortexNamespace, g))
+				}
+
+				if err := ruler.SetRuleGroup(log, cortexNamespace, g); err != nil {
+					log.Error(err, "unable to set rule group")
+
+					diff := r.diff(log, ruler, cortexNamespace, g)
+					if err := r.patchStatus(ctx, rule, func(status *monitoringv1.PrometheusRuleStatus) {
+						status.SyncStatus = fmt.Sprintf("unable to set rule group: %v", err)
+						status.Diff = truncate(diff, maxStatusDiffLength)
+					}); err != nil {
+						log.Error(err, "unable to set status")
+						return ctrl.Result{}, err
+					}
+					return ctrl.Result{}, err
+				}
+			}
+
+			// Removes disabled groups, groups dropped from the spec and split groups that shrank.
+			if err := pruneRuleGroups(log, ruler, cortexNamespace, stale); err != nil {
+				log.Error(err, "unable to delete stale rule groups")
+
+				if err := r.setStatus(ctx, rule, fmt.Sprintf("unable to delete stale rule groups: %v", err)); err != nil {
+					log.Error(err, "unable to set status")
+					return ctrl.Result{}, err
+				}
+				return ctrl.Result{}, err
+			}
 		}
+
+		// The health is only refreshed once per interval, as the status patch triggers another reconciliation.
+		var health *monitoringv1.RuleHealthStatus
+		var alerts *monitoringv1.AlertSummary
+		requeueAfter, healthDue := r.healthCheckDue(last)
+		if healthDue {
+			if health, alerts, err = r.checkHealth(rule, log, ruler, cortexNamespace); err != nil {
+				log.Error(err, "unable to check rule health")
+			}
+		}
+
+		if err := r.patchStatus(ctx, rule, func(status *monitoringv1.PrometheusRuleStatus) {
+			status.SyncStatus = syncedStatus(ruler)
+			if !upToDate && !ruler.DryRun() {
+				now := metav1.Now()
+				status.LastSyncTime = &now
+				status.SyncedChecksum = checksum
+			}
+			status.CortexNamespace = cortexNamespace
+			status.Tenant = ruler.ID()
+			status.Groups = len(result.Groups)
+			status.Rules = countRules(result.Groups)
+			status.RewrittenRules = result.Rewritten
+			status.Skipped = result.Skipped
+			if !upToDate {
+				status.Diff = truncate(strings.Join(diffs, ""), maxStatusDiffLength)
+			}
+			if len(rule.RuleSpec().Tests) == 0 {
+				status.TestFailures = nil
+				meta.RemoveStatusCondition(&status.Conditions, monitoringv1.ConditionTestsPassed)
+			}
+			status.PolicyViolations = nil
+			meta.SetStatusCondition(&status.Conditions, policyCompliantCondition(nil))
+			meta.SetStatusCondition(&status.Conditions, quotaExceededCondition(nil))
+			meta.SetStatusCondition(&status.Conditions, pausedCondition(false))
+			if healthDue {
+				if health != nil {
+					status.Health = health
+				}
+				if alerts != nil {
+					status.Alerts = alerts
+				}
+				meta.SetStatusCondition(&status.Conditions, healthyCondition(health))
+			}
+		}); err != nil {
+			log.Error(err, "unable to set status")
+			return ctrl.Result{}, err
+		}
+
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
 	}
 
 	return ctrl.Result{}, nil
 }
 
+// healthCheckDue reports whether the health recorded in status is older than the HealthCheckInterval,
+// and otherwise how long until it is.
+func (r *PrometheusRuleReconciler) healthCheckDue(status *monitoringv1.PrometheusRuleStatus) (time.Duration, bool) {
+	if r.HealthCheckInterval <= 0 {
+		return 0, false
+	}
+	if status.Health == nil {
+		return r.HealthCheckInterval, true
+	}
+	if remaining := r.HealthCheckInterval - time.Since(status.Health.LastChecked.Time); remaining > 0 {
+		return remaining, false
+	}
+	return r.HealthCheckInterval, true
+}
+
+// recordDryRun logs and records an event with the changes SetRuleGroup would apply to group.
+// It returns the diff of the changes.
+func (r *PrometheusRuleReconciler) recordDryRun(rule monitoringv1.RuleObject, log logr.Logger, ruler *cortex.Client, cortexNamespace string, g monitoringv1.RuleGroup) string {
+	diff := r.diff(log, ruler, cortexNamespace, g)
+	if diff == "" {
+		return ""
+	}
+
+	log.Info("dry-run: rule group would change", "group", g.Name, "diff", diff)
+	r.Recorder.Event(rule, corev1.EventTypeNormal, "DryRun", truncate(fmt.Sprintf("rule group %s would change:\n%s", g.Name, diff), maxEventMessageLength))
+	return diff
+}
+
+// diff returns the diff between group and the group stored in Cortex, or an empty string if it cannot be determined.
+func (r *PrometheusRuleReconciler) diff(log logr.Logger, ruler *cortex.Client, cortexNamespace string, g monitoringv1.RuleGroup) string {
+	diff, err := ruler.DiffRuleGroup(log, cortexNamespace, g)
+	if err != nil {
+		log.Error(err, "unable to diff rule group")
+		return ""
+	}
+	return diff
+}
+
 // setStatus sets PrometheusStatus.
-func (r *PrometheusRuleReconciler) setStatus(ctx context.Context, rule monitoringv1.PrometheusRule, status string) error {
-	newRule := rule.DeepCopy()
-	newRule.Status.SyncStatus = status
-	if err := r.Patch(ctx, newRule, client.MergeFrom(&rule)); err != nil {
+func (r *PrometheusRuleReconciler) setStatus(ctx context.Context, rule monitoringv1.RuleObject, status string) error {
+	return r.patchStatus(ctx, rule, func(s *monitoringv1.PrometheusRuleStatus) {
+		s.SyncStatus = status
+	})
+}
+
+// patchStatus applies mutate to the status of the current PrometheusRule.
+func (r *PrometheusRuleReconciler) patchStatus(ctx context.Context, rule monitoringv1.RuleObject, mutate func(*monitoringv1.PrometheusRuleStatus)) error {
+	newRule := rule.DeepCopyObject().(monitoringv1.RuleObject)
+	mutate(newRule.RuleStatus())
+	if err := r.Status().Patch(ctx, newRule, client.MergeFrom(rule)); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// setPaused sets the Paused condition of the current PrometheusRule.
+func (r *PrometheusRuleReconciler) setPaused(ctx context.Context, rule monitoringv1.RuleObject, paused bool) error {
+	return r.patchStatus(ctx, rule, func(status *monitoringv1.PrometheusRuleStatus) {
+		meta.SetStatusCondition(&status.Conditions, pausedCondition(paused))
+	})
+}
+
+// isPaused checks if writes to Cortex are paused for the current PrometheusRule, either by annotation or operator-wide.
+func (r *PrometheusRuleReconciler) isPaused(rule monitoringv1.RuleObject) bool {
+	if r.Paused {
+		return true
+	}
+	paused, _ := strconv.ParseBool(rule.GetAnnotations()[monitoringv1.PausedAnnotation])
+	return paused
+}
+
+// ruler returns the client of the ruler the current PrometheusRule is synced to,
+// or nil if no ruler is configured for its backend.
+func (r *PrometheusRuleReconciler) ruler(rule monitoringv1.RuleObject) *cortex.Client {
+	if rule.RuleSpec().Backend == monitoringv1.BackendLoki {
+		return r.Loki
+	}
+	return r.Cortex
+}
+
+// deletionPolicy returns the deletion policy of the current PrometheusRule, falling back to the operator default.
+func (r *PrometheusRuleReconciler) deletionPolicy(rule monitoringv1.RuleObject) monitoringv1.DeletionPolicy {
+	if rule.RuleSpec().DeletionPolicy != "" {
+		return rule.RuleSpec().DeletionPolicy
+	}
+	if r.DeletionPolicy != "" {
+		return r.DeletionPolicy
+	}
+	return monitoringv1.DeletionPolicyDelete
+}
+
 // hasFinalizer checks if PrometheusRule has our finalizer set.
-func (r *PrometheusRuleReconciler) hasFinalizer(rule monitoringv1.PrometheusRule) bool {
-	return containsString(rule.ObjectMeta.Finalizers, finalizerName)
+func (r *PrometheusRuleReconciler) hasFinalizer(rule monitoringv1.RuleObject) bool {
+	return containsString(rule.GetFinalizers(), finalizerName)
 }
 
 // isDeletionScheduled checks if the current PrometheusRule is scheduled for deletion.
 // That means a deletion timestamp is set, but it is not completely deleted as there may be finalizers on that object.
-func (r *PrometheusRuleReconciler) isDeletionScheduled(rule monitoringv1.PrometheusRule) bool {
-	return !rule.ObjectMeta.DeletionTimestamp.IsZero()
+func (r *PrometheusRuleReconciler) isDeletionScheduled(rule monitoringv1.RuleObject) bool {
+	return !rule.GetDeletionTimestamp().IsZero()
 }
 
 // removeFinalizer removes our finalizer from the current PrometheusRule.
-func (r *PrometheusRuleReconciler) removeFinalizer(ctx context.Context, rule monitoringv1.PrometheusRule, log logr.Logger) error {
+func (r *PrometheusRuleReconciler) removeFinalizer(ctx context.Context, rule monitoringv1.RuleObject, log logr.Logger) error {
 	log.Info("Removing finalizer")
 
-	newRule := rule.DeepCopy()
-	newRule.ObjectMeta.Finalizers = removeString(rule.ObjectMeta.Finalizers, finalizerName)
-	if err := r.Patch(ctx, newRule, client.MergeFrom(&rule)); err != nil {
+	newRule := rule.DeepCopyObject().(monitoringv1.RuleObject)
+	newRule.SetFinalizers(removeString(rule.GetFinalizers(), finalizerName))
+	if err := r.Patch(ctx, newRule, client.MergeFrom(rule)); err != nil {
 		return err
 	}
 
@@ -144,25 +536,188 @@ func (r *PrometheusRuleReconciler) removeFinalizer(ctx context.Context, rule mon
 }
 
 // addFinalizer patches the current PrometheusRule, so that it contains our finalizer.
-func (r *PrometheusRuleReconciler) addFinalizer(ctx context.Context, rule monitoringv1.PrometheusRule, log logr.Logger) error {
+func (r *PrometheusRuleReconciler) addFinalizer(ctx context.Context, rule monitoringv1.RuleObject, log logr.Logger) error {
 	log.Info("Adding finalizer")
 
-	newRule := rule.DeepCopy()
-	newRule.ObjectMeta.Finalizers = append(newRule.ObjectMeta.Finalizers, finalizerName)
-	if err := r.Patch(ctx, newRule, client.MergeFrom(&rule)); err != nil {
+	newRule := rule.DeepCopyObject().(monitoringv1.RuleObject)
+	newRule.SetFinalizers(append(newRule.GetFinalizers(), finalizerName))
+	if err := r.Patch(ctx, newRule, client.MergeFrom(rule)); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// templateLookup returns a lookup of the RuleTemplates in namespace.
+func (r *PrometheusRuleReconciler) templateLookup(ctx context.Context, namespace string) render.TemplateLookup {
+	return func(name string) (*monitoringv1.RuleTemplate, error) {
+		var tmpl monitoringv1.RuleTemplate
+		if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &tmpl); err != nil {
+			return nil, err
+		}
+		return &tmpl, nil
+	}
+}
+
+// requestsForTemplate enqueues the PrometheusRules instantiating a RuleTemplate, so they are re-synced when it changes.
+func (r *PrometheusRuleReconciler) requestsForTemplate(obj client.Object) []reconcile.Request {
+	var list monitoringv1.PrometheusRuleList
+	if err := r.List(context.Background(), &list, client.InNamespace(obj.GetNamespace())); err != nil {
+		r.Log.Error(err, "unable to list PrometheusRules")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, rule := range list.Items {
+		for _, instance := range rule.Spec.Templates {
+			if instance.Name == obj.GetName() {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: rule.Namespace, Name: rule.Name}})
+				break
+			}
+		}
+	}
+	return requests
+}
+
+// requestsForAll enqueues every PrometheusRule, so they are checked against a changed RulePolicy.
+func (r *PrometheusRuleReconciler) requestsForAll(client.Object) []reconcile.Request {
+	var list monitoringv1.PrometheusRuleList
+	if err := r.List(context.Background(), &list); err != nil {
+		r.Log.Error(err, "unable to list PrometheusRules")
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(list.Items))
+	for _, rule := range list.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: rule.Namespace, Name: rule.Name}})
+	}
+	return requests
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *PrometheusRuleReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&monitoringv1.PrometheusRule{}).
+		Watches(&source.Kind{Type: &monitoringv1.RuleTemplate{}}, handler.EnqueueRequestsFromMapFunc(r.requestsForTemplate)).
+		Watches(&source.Kind{Type: &monitoringv1.RulePolicy{}}, handler.EnqueueRequestsFromMapFunc(r.requestsForAll)).
 		Complete(r)
 }
 
+func pausedCondition(paused bool) metav1.Condition {
+	if paused {
+		return metav1.Condition{
+			Type:    monitoringv1.ConditionPaused,
+			Status:  metav1.ConditionTrue,
+			Reason:  "Paused",
+			Message: "Rules in Cortex are not changed while paused",
+		}
+	}
+	return metav1.Condition{
+		Type:   monitoringv1.ConditionPaused,
+		Status: metav1.ConditionFalse,
+		Reason: "Reconciling",
+	}
+}
+
+func testsPassedCondition(failures []string) metav1.Condition {
+	if len(failures) > 0 {
+		return metav1.Condition{
+			Type:    monitoringv1.ConditionTestsPassed,
+			Status:  metav1.ConditionFalse,
+			Reason:  "TestsFailed",
+			Message: fmt.Sprintf("%d rule test expectations failed", len(failures)),
+		}
+	}
+	return metav1.Condition{
+		Type:   monitoringv1.ConditionTestsPassed,
+		Status: metav1.ConditionTrue,
+		Reason: "TestsPassed",
+	}
+}
+
+// syncChecksum identifies the rule groups and tests written to cortexNamespace, and the tenant and ruler they are written to.
+func syncChecksum(ruler *cortex.Client, backend monitoringv1.Backend, cortexNamespace string, groups []monitoringv1.RuleGroup, tests []monitoringv1.RuleTest) (string, error) {
+	data, err := json.Marshal(struct {
+		Tenant          string                   `json:"tenant"`
+		Address         string                   `json:"address"`
+		Backend         monitoringv1.Backend     `json:"backend"`
+		CortexNamespace string                   `json:"cortexNamespace"`
+		Groups          []monitoringv1.RuleGroup `json:"groups"`
+		Tests           []monitoringv1.RuleTest  `json:"tests"`
+	}{ruler.ID(), ruler.Address(), backend, cortexNamespace, groups, tests})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data)), nil
+}
+
+// syncedStatus is the sync status after a successful sync to ruler. In dry-run mode nothing was written,
+// which must not be mistaken for a live change.
+func syncedStatus(ruler *cortex.Client) string {
+	if ruler.DryRun() {
+		return "dry-run"
+	}
+	return "synced"
+}
+
+// outdatedRuleGroups returns the groups in desired that are missing from current or differ from the stored ones.
+func outdatedRuleGroups(current []cortex.RuleGroup, desired []monitoringv1.RuleGroup) []monitoringv1.RuleGroup {
+	stored := make(map[string]cortex.RuleGroup, len(current))
+	for _, g := range current {
+		stored[g.Name] = g
+	}
+
+	var outdated []monitoringv1.RuleGroup
+	for _, g := range desired {
+		if s, ok := stored[g.Name]; !ok || !s.Equal(cortex.NewRuleGroup(g)) {
+			outdated = append(outdated, g)
+		}
+	}
+	return outdated
+}
+
+// staleRuleGroups returns the names of the groups in current that are not in desired.
+func staleRuleGroups(current []cortex.RuleGroup, desired []monitoringv1.RuleGroup) []string {
+	keep := make(map[string]bool, len(desired))
+	for _, g := range desired {
+		keep[g.Name] = true
+	}
+
+	var stale []string
+	for _, g := range current {
+		if !keep[g.Name] {
+			stale = append(stale, g.Name)
+		}
+	}
+	return stale
+}
+
+// pruneRuleGroups deletes the stale rule groups from cortexNamespace.
+func pruneRuleGroups(log logr.Logger, ruler *cortex.Client, cortexNamespace string, stale []string) error {
+	for _, name := range stale {
+		log.Info("deleting stale rule group", "group", name)
+		if err := ruler.DeleteRuleGroup(log, cortexNamespace, name); err != nil && !errors.Is(err, cortex.ErrResourceNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+func countRules(groups []monitoringv1.RuleGroup) int {
+	var n int
+	for _, g := range groups {
+		n += len(g.Rules)
+	}
+	return n
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-3] + "..."
+}
+
 func containsString(slice []string, s string) bool {
 	for _, item := range slice {
 		if item == s {
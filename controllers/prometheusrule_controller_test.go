@@ -6,12 +6,16 @@ import (
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
-	"github.com/onsi/gomega/ghttp"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	monitoringv1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+	"github.com/bolindalabs/cortex-alert-operator/controllers/cortex"
 )
 
 var _ = Describe("PrometheusRule Controller", func() {
@@ -27,37 +31,9 @@ var _ = Describe("PrometheusRule Controller", func() {
 	Context("When creating PrometheusRule", func() {
 		It("Should call Cortex API with the right arguments", func() {
 			By("By applying a new PrometheusRule")
-			server.AppendHandlers(
-				ghttp.VerifyRequest("POST", "/api/v1/rules/default--test-prometheusrule"),
-			)
-			server.AppendHandlers(
-				ghttp.VerifyRequest("POST", "/api/v1/rules/default--test-prometheusrule"),
-			)
-
+			ruler := serveRules()
 			ctx := context.Background()
-			prometheusRule := &monitoringv1.PrometheusRule{
-				TypeMeta: metav1.TypeMeta{
-					Kind:       "PrometheusRule",
-					APIVersion: "monitoring.bolinda.digital/v1",
-				},
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      PrometheusRuleName,
-					Namespace: PrometheusRuleNamespace,
-				},
-				Spec: monitoringv1.PrometheusRuleSpec{
-					Groups: []monitoringv1.RuleGroup{
-						{
-							Name: "./example.rules",
-							Rules: []monitoringv1.Rule{
-								{
-									Alert: "ExampleAlert",
-									Expr:  intstr.FromString("vector(1)"),
-								},
-							},
-						},
-					},
-				},
-			}
+			prometheusRule := newPrometheusRule(PrometheusRuleName, PrometheusRuleNamespace)
 			Expect(k8sClient.Create(ctx, prometheusRule)).Should(Succeed())
 
 			prometheusRuleLookupKey := types.NamespacedName{Name: PrometheusRuleName, Namespace: PrometheusRuleNamespace}
@@ -68,10 +44,316 @@ var _ = Describe("PrometheusRule Controller", func() {
 				return err == nil
 			}, timeout, interval).Should(BeTrue(), "PrometheusRule should be stored and retrievable from K8s")
 
-			Eventually(func() int {
-				req := server.ReceivedRequests()
-				return len(req)
-			}, timeout, interval).Should(Equal(2))
+			Eventually(func() []cortex.RuleGroup {
+				return ruler.groups("default--test-prometheusrule")
+			}, timeout, interval).Should(HaveLen(1))
+			group := ruler.groups("default--test-prometheusrule")[0]
+			Expect(group.Name).To(Equal("./example.rules"))
+			Expect(group.Rules).To(HaveLen(1))
+			Expect(group.Rules[0].Alert).To(Equal("ExampleAlert"))
+			Expect(group.Rules[0].Expr).To(Equal("vector(1)"))
+
+			// Unchanged rules are not written again.
+			Consistently(func() int {
+				return countRequests("POST", rulesPath+"default--test-prometheusrule")
+			}, time.Second*2, interval).Should(Equal(1))
+
+			By("By changing the rule group in Cortex directly")
+			changed := group
+			changed.Rules = []cortex.Rule{{Alert: "ExampleAlert", Expr: "vector(0)"}}
+			ruler.set("default--test-prometheusrule", changed)
+			updatePrometheusRule(ctx, prometheusRuleLookupKey, func(rule *monitoringv1.PrometheusRule) {
+				rule.Annotations = map[string]string{"example.com/touched": "true"}
+			})
+			Eventually(func() string {
+				groups := ruler.groups("default--test-prometheusrule")
+				if len(groups) != 1 || len(groups[0].Rules) != 1 {
+					return ""
+				}
+				return groups[0].Rules[0].Expr
+			}, timeout, interval).Should(Equal("vector(1)"), "the rule group changed in Cortex should be restored")
+			Expect(countRequests("POST", rulesPath+"default--test-prometheusrule")).To(Equal(2))
+
+			deletePrometheusRule(ctx, prometheusRule)
+		})
+	})
+
+	Context("When the PrometheusRule is paused", func() {
+		It("Should leave Cortex untouched until it is resumed", func() {
+			ruler := serveRules()
+			ctx := context.Background()
+			prometheusRule := newPrometheusRule("paused-prometheusrule", PrometheusRuleNamespace)
+			Expect(k8sClient.Create(ctx, prometheusRule)).Should(Succeed())
+			Eventually(func() []cortex.RuleGroup {
+				return ruler.groups("default--paused-prometheusrule")
+			}, timeout, interval).Should(HaveLen(1))
+
+			By("By pausing the PrometheusRule")
+			lookupKey := types.NamespacedName{Name: "paused-prometheusrule", Namespace: PrometheusRuleNamespace}
+			updatePrometheusRule(ctx, lookupKey, func(rule *monitoringv1.PrometheusRule) {
+				rule.Annotations = map[string]string{monitoringv1.PausedAnnotation: "true"}
+			})
+			Eventually(func() bool {
+				var paused monitoringv1.PrometheusRule
+				if err := k8sClient.Get(ctx, lookupKey, &paused); err != nil {
+					return false
+				}
+				return meta.IsStatusConditionTrue(paused.Status.Conditions, monitoringv1.ConditionPaused)
+			}, timeout, interval).Should(BeTrue(), "PrometheusRule should report being paused")
+
+			By("By deleting its rule group in Cortex while it is paused")
+			ruler.set("default--paused-prometheusrule")
+			writes := countRequests("POST", rulesPath+"default--paused-prometheusrule")
+			updatePrometheusRule(ctx, lookupKey, func(rule *monitoringv1.PrometheusRule) {
+				rule.Annotations["example.com/touched"] = "true"
+			})
+			Consistently(func() int {
+				return countRequests("POST", rulesPath+"default--paused-prometheusrule")
+			}, time.Second*2, interval).Should(Equal(writes))
+			Expect(ruler.groups("default--paused-prometheusrule")).To(BeEmpty())
+
+			By("By resuming the PrometheusRule")
+			updatePrometheusRule(ctx, lookupKey, func(rule *monitoringv1.PrometheusRule) {
+				delete(rule.Annotations, monitoringv1.PausedAnnotation)
+			})
+			Eventually(func() []cortex.RuleGroup {
+				return ruler.groups("default--paused-prometheusrule")
+			}, timeout, interval).Should(HaveLen(1), "the rule group deleted in Cortex should be restored")
+
+			deletePrometheusRule(ctx, prometheusRule)
+		})
+	})
+
+	Context("When deleting a PrometheusRule with the Retain deletion policy", func() {
+		It("Should keep its rule groups in Cortex, also when it is created again", func() {
+			ruler := serveRules()
+			ctx := context.Background()
+			prometheusRule := newPrometheusRule("retained-prometheusrule", PrometheusRuleNamespace)
+			prometheusRule.Spec.DeletionPolicy = monitoringv1.DeletionPolicyRetain
+			Expect(k8sClient.Create(ctx, prometheusRule)).Should(Succeed())
+			Eventually(func() []cortex.RuleGroup {
+				return ruler.groups("default--retained-prometheusrule")
+			}, timeout, interval).Should(HaveLen(1))
+
+			By("By deleting the PrometheusRule")
+			deletePrometheusRule(ctx, prometheusRule)
+			Expect(countRequests("DELETE", rulesPath+"default--retained-prometheusrule")).To(BeZero())
+			Expect(ruler.groups("default--retained-prometheusrule")).To(HaveLen(1))
+
+			By("By creating it again with a changed rule")
+			prometheusRule = newPrometheusRule("retained-prometheusrule", PrometheusRuleNamespace)
+			prometheusRule.Spec.DeletionPolicy = monitoringv1.DeletionPolicyRetain
+			prometheusRule.Spec.Groups[0].Rules[0].Expr = intstr.FromString("vector(2)")
+			Expect(k8sClient.Create(ctx, prometheusRule)).Should(Succeed())
+			Eventually(func() []string {
+				var exprs []string
+				for _, g := range ruler.groups("default--retained-prometheusrule") {
+					for _, r := range g.Rules {
+						exprs = append(exprs, r.Expr)
+					}
+				}
+				return exprs
+			}, timeout, interval).Should(Equal([]string{"vector(2)"}))
+
+			deletePrometheusRule(ctx, prometheusRule)
+			Expect(countRequests("DELETE", rulesPath+"default--retained-prometheusrule")).To(BeZero())
+			Expect(ruler.groups("default--retained-prometheusrule")).To(HaveLen(1))
+		})
+	})
+
+	Context("When the Cortex client is in dry-run mode", func() {
+		It("Should leave the rule groups in Cortex unchanged", func() {
+			ruler := serveRules()
+			outdated := cortex.NewRuleGroup(newPrometheusRule("dry-run-prometheusrule", PrometheusRuleNamespace).Spec.Groups[0])
+			outdated.Rules[0].Expr = "vector(0)"
+			stale := cortex.RuleGroup{Name: "stale.rules", Rules: []cortex.Rule{{Record: "stale", Expr: "vector(1)"}}}
+			ruler.set("default--dry-run-prometheusrule", outdated, stale)
+
+			dryRunClient, err := cortex.New(cortex.Config{Address: server.URL(), DryRun: true})
+			Expect(err).ToNot(HaveOccurred())
+			prometheusRuleReconciler.Cortex = dryRunClient
+
+			ctx := context.Background()
+			prometheusRule := newPrometheusRule("dry-run-prometheusrule", PrometheusRuleNamespace)
+			Expect(k8sClient.Create(ctx, prometheusRule)).Should(Succeed())
+
+			lookupKey := types.NamespacedName{Name: "dry-run-prometheusrule", Namespace: PrometheusRuleNamespace}
+			Eventually(func() string {
+				var created monitoringv1.PrometheusRule
+				if err := k8sClient.Get(ctx, lookupKey, &created); err != nil {
+					return ""
+				}
+				return created.Status.SyncStatus
+			}, timeout, interval).Should(Equal("dry-run"))
+
+			Consistently(func() []cortex.RuleGroup {
+				return ruler.groups("default--dry-run-prometheusrule")
+			}, time.Second*2, interval).Should(Equal([]cortex.RuleGroup{outdated, stale}))
+			Expect(countRequests("POST", rulesPath)).To(BeZero())
+			Expect(countRequests("DELETE", rulesPath)).To(BeZero())
+
+			By("By deleting the PrometheusRule before the dry-run client is replaced")
+			deletePrometheusRule(ctx, prometheusRule)
+			Expect(countRequests("DELETE", rulesPath)).To(BeZero())
+			Expect(ruler.groups("default--dry-run-prometheusrule")).To(Equal([]cortex.RuleGroup{outdated, stale}))
+		})
+	})
+
+	Context("When the PrometheusRule starts violating a RulePolicy", func() {
+		It("Should keep the synced rule groups in Cortex", func() {
+			ruler := serveRules()
+			ctx := context.Background()
+			applyPolicy(ctx, "policy-test", monitoringv1.RulePolicySpec{RequiredLabels: []string{"team"}})
+
+			prometheusRule := newPrometheusRule("violating-prometheusrule", "policy-test")
+			prometheusRule.Spec.Groups[0].Rules[0].Labels = map[string]string{"team": "payments"}
+			Expect(k8sClient.Create(ctx, prometheusRule)).Should(Succeed())
+			Eventually(func() []cortex.RuleGroup {
+				return ruler.groups("policy-test--violating-prometheusrule")
+			}, timeout, interval).Should(HaveLen(1))
+			writes := countRequests("POST", rulesPath+"policy-test--violating-prometheusrule")
+
+			By("By dropping the required label")
+			lookupKey := types.NamespacedName{Name: "violating-prometheusrule", Namespace: "policy-test"}
+			updatePrometheusRule(ctx, lookupKey, func(rule *monitoringv1.PrometheusRule) {
+				rule.Spec.Groups[0].Rules[0].Labels = nil
+			})
+			Eventually(func() string {
+				var violating monitoringv1.PrometheusRule
+				if err := k8sClient.Get(ctx, lookupKey, &violating); err != nil {
+					return ""
+				}
+				return violating.Status.SyncStatus
+			}, timeout, interval).Should(Equal("rule policies violated"))
+
+			Consistently(func() int {
+				return countRequests("POST", rulesPath+"policy-test--violating-prometheusrule")
+			}, time.Second*2, interval).Should(Equal(writes))
+			Expect(countRequests("DELETE", rulesPath+"policy-test--violating-prometheusrule")).To(BeZero())
+			groups := ruler.groups("policy-test--violating-prometheusrule")
+			Expect(groups).To(HaveLen(1))
+			Expect(groups[0].Rules[0].Labels).To(HaveKeyWithValue("team", "payments"))
+
+			deletePrometheusRule(ctx, prometheusRule)
+		})
+	})
+
+	Context("When the PrometheusRule grows beyond the quota of its namespace", func() {
+		It("Should keep the synced rule groups in Cortex", func() {
+			ruler := serveRules()
+			ctx := context.Background()
+			applyPolicy(ctx, "quota-test", monitoringv1.RulePolicySpec{Quota: &monitoringv1.RuleQuota{MaxRules: 1}})
+
+			prometheusRule := newPrometheusRule("exceeding-prometheusrule", "quota-test")
+			Expect(k8sClient.Create(ctx, prometheusRule)).Should(Succeed())
+			Eventually(func() []cortex.RuleGroup {
+				return ruler.groups("quota-test--exceeding-prometheusrule")
+			}, timeout, interval).Should(HaveLen(1))
+			writes := countRequests("POST", rulesPath+"quota-test--exceeding-prometheusrule")
+
+			By("By adding a second rule")
+			lookupKey := types.NamespacedName{Name: "exceeding-prometheusrule", Namespace: "quota-test"}
+			updatePrometheusRule(ctx, lookupKey, func(rule *monitoringv1.PrometheusRule) {
+				g := &rule.Spec.Groups[0]
+				g.Rules = append(g.Rules, monitoringv1.Rule{Alert: "OtherAlert", Expr: intstr.FromString("vector(1)")})
+			})
+			Eventually(func() bool {
+				var exceeding monitoringv1.PrometheusRule
+				if err := k8sClient.Get(ctx, lookupKey, &exceeding); err != nil {
+					return false
+				}
+				return meta.IsStatusConditionTrue(exceeding.Status.Conditions, monitoringv1.ConditionQuotaExceeded)
+			}, timeout, interval).Should(BeTrue(), "PrometheusRule should report the exceeded quota")
+
+			Consistently(func() int {
+				return countRequests("POST", rulesPath+"quota-test--exceeding-prometheusrule")
+			}, time.Second*2, interval).Should(Equal(writes))
+			Expect(countRequests("DELETE", rulesPath+"quota-test--exceeding-prometheusrule")).To(BeZero())
+			groups := ruler.groups("quota-test--exceeding-prometheusrule")
+			Expect(groups).To(HaveLen(1))
+			Expect(groups[0].Rules).To(HaveLen(1))
+
+			deletePrometheusRule(ctx, prometheusRule)
 		})
 	})
 })
+
+// newPrometheusRule returns a PrometheusRule with a single alerting rule.
+func newPrometheusRule(name, namespace string) *monitoringv1.PrometheusRule {
+	return &monitoringv1.PrometheusRule{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "PrometheusRule",
+			APIVersion: "monitoring.bolinda.digital/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: monitoringv1.PrometheusRuleSpec{
+			Groups: []monitoringv1.RuleGroup{
+				{
+					Name: "./example.rules",
+					Rules: []monitoringv1.Rule{
+						{
+							Alert: "ExampleAlert",
+							Expr:  intstr.FromString("vector(1)"),
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// updatePrometheusRule applies mutate to the stored PrometheusRule at key, retrying on conflicts.
+func updatePrometheusRule(ctx context.Context, key types.NamespacedName, mutate func(*monitoringv1.PrometheusRule)) {
+	Eventually(func() error {
+		var rule monitoringv1.PrometheusRule
+		if err := k8sClient.Get(ctx, key, &rule); err != nil {
+			return err
+		}
+		mutate(&rule)
+		return k8sClient.Update(ctx, &rule)
+	}, time.Second*10, time.Millisecond*250).Should(Succeed())
+}
+
+// deletePrometheusRule deletes rule and waits until the reconciler removed its finalizer.
+func deletePrometheusRule(ctx context.Context, rule *monitoringv1.PrometheusRule) {
+	Expect(k8sClient.Delete(ctx, rule)).Should(Succeed())
+	Eventually(func() bool {
+		var deleted monitoringv1.PrometheusRule
+		return apierrors.IsNotFound(k8sClient.Get(ctx, client.ObjectKeyFromObject(rule), &deleted))
+	}, time.Second*10, time.Millisecond*250).Should(BeTrue(), "PrometheusRule should be deleted")
+}
+
+// applyPolicy creates a namespace and a RulePolicy with spec selecting it. It returns once the
+// reconcilers see both, so PrometheusRules created afterwards are checked against the policy.
+// The policy is kept, deleting it would reconcile the PrometheusRules of later specs.
+func applyPolicy(ctx context.Context, namespace string, spec monitoringv1.RulePolicySpec) {
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:   namespace,
+		Labels: map[string]string{"policy": namespace},
+	}}
+	Expect(k8sClient.Create(ctx, ns)).Should(Succeed())
+
+	spec.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"policy": namespace}}
+	policy := &monitoringv1.RulePolicy{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "RulePolicy",
+			APIVersion: "monitoring.bolinda.digital/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: namespace,
+		},
+		Spec: spec,
+	}
+	Expect(k8sClient.Create(ctx, policy)).Should(Succeed())
+
+	// k8sClient reads from the cache of the manager, like the reconcilers.
+	Eventually(func() error {
+		if err := k8sClient.Get(ctx, types.NamespacedName{Name: namespace}, &corev1.Namespace{}); err != nil {
+			return err
+		}
+		return k8sClient.Get(ctx, types.NamespacedName{Name: namespace}, &monitoringv1.RulePolicy{})
+	}, time.Second*10, time.Millisecond*250).Should(Succeed())
+}
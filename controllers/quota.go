@@ -0,0 +1,165 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	monitoringv1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+	"github.com/bolindalabs/cortex-alert-operator/controllers/cortex"
+	"github.com/bolindalabs/cortex-alert-operator/controllers/policy"
+)
+
+// QuotaLimits are the operator-wide rule quotas. Zero values are unlimited.
+type QuotaLimits struct {
+	// Namespace limits the rules of every Kubernetes namespace. RulePolicies may set stricter quotas.
+	Namespace monitoringv1.RuleQuota
+	// Tenant limits the rules of all PrometheusRules and ClusterPrometheusRules synced to the same ruler.
+	Tenant monitoringv1.RuleQuota
+}
+
+// rulerLimits returns the configured limits of ruler, completed by the limits it reports.
+// The ruler is only asked if the limits are used, to split groups or to complete the quotas.
+func (r *PrometheusRuleReconciler) rulerLimits(log logr.Logger, ruler *cortex.Client) cortex.RulerLimits {
+	if !r.SplitGroups && r.Quota == (QuotaLimits{}) {
+		return r.RulerLimits
+	}
+	return completeRulerLimits(log, ruler, r.RulerLimits)
+}
+
+// completeRulerLimits returns configured, with unset limits taken from the limits ruler reports.
+func completeRulerLimits(log logr.Logger, ruler *cortex.Client, configured cortex.RulerLimits) cortex.RulerLimits {
+	limits := configured
+	if limits.MaxRulesPerRuleGroup > 0 && limits.MaxRuleGroupsPerTenant > 0 {
+		return limits
+	}
+
+	reported, err := ruler.GetRulerLimits(log)
+	if err != nil {
+		log.Error(err, "unable to get ruler limits")
+		return limits
+	}
+	if limits.MaxRulesPerRuleGroup == 0 {
+		limits.MaxRulesPerRuleGroup = reported.MaxRulesPerRuleGroup
+	}
+	if limits.MaxRuleGroupsPerTenant == 0 {
+		limits.MaxRuleGroupsPerTenant = reported.MaxRuleGroupsPerTenant
+	}
+	return limits
+}
+
+// quotaLimits returns quota, with the limits of the ruler added to the tenant quota,
+// so rule groups the ruler would reject are reported before they are sent.
+func quotaLimits(quota QuotaLimits, limits cortex.RulerLimits) QuotaLimits {
+	q := quota
+	q.Tenant = policy.Stricter(q.Tenant, monitoringv1.RuleQuota{
+		MaxGroups:        limits.MaxRuleGroupsPerTenant,
+		MaxRulesPerGroup: limits.MaxRulesPerRuleGroup,
+	})
+	return q
+}
+
+// checkQuota returns how groups, the rendered groups of rule, exceed limits or the quotas of policies.
+// The usage of the other rules is taken from the counts in their status, which reflect what is stored in the ruler.
+func checkQuota(ctx context.Context, c client.Reader, rule monitoringv1.RuleObject, groups []monitoringv1.RuleGroup, policies []monitoringv1.RulePolicy, limits QuotaLimits) ([]string, error) {
+	var exceeded []string
+
+	if q := policy.EffectiveQuota(limits.Namespace, policies); q != (monitoringv1.RuleQuota{}) {
+		others, err := otherRulesUsage(ctx, c, rule, true)
+		if err != nil {
+			return nil, err
+		}
+
+		scope := "namespace " + rule.GetNamespace()
+		if rule.GetNamespace() == "" {
+			scope = "cluster-scoped rules"
+		}
+		exceeded = append(exceeded, policy.CheckQuota(q, scope, groups, others)...)
+	}
+
+	if limits.Tenant != (monitoringv1.RuleQuota{}) {
+		others, err := otherRulesUsage(ctx, c, rule, false)
+		if err != nil {
+			return nil, err
+		}
+		exceeded = append(exceeded, policy.CheckQuota(limits.Tenant, "tenant", groups, others)...)
+	}
+	return exceeded, nil
+}
+
+// otherRulesUsage sums the synced rule groups and rules of the rules of the same backend as rule,
+// except rule itself, either in the namespace of rule or in the whole tenant.
+func otherRulesUsage(ctx context.Context, c client.Reader, rule monitoringv1.RuleObject, inNamespace bool) (policy.Usage, error) {
+	var rules []monitoringv1.RuleObject
+	if !inNamespace || rule.GetNamespace() != "" {
+		var list monitoringv1.PrometheusRuleList
+		var opts []client.ListOption
+		if inNamespace {
+			opts = append(opts, client.InNamespace(rule.GetNamespace()))
+		}
+		if err := c.List(ctx, &list, opts...); err != nil {
+			return policy.Usage{}, err
+		}
+		for i := range list.Items {
+			rules = append(rules, &list.Items[i])
+		}
+	}
+	if !inNamespace || rule.GetNamespace() == "" {
+		var list monitoringv1.ClusterPrometheusRuleList
+		if err := c.List(ctx, &list); err != nil {
+			return policy.Usage{}, err
+		}
+		for i := range list.Items {
+			rules = append(rules, &list.Items[i])
+		}
+	}
+
+	isLoki := rule.RuleSpec().Backend == monitoringv1.BackendLoki
+	var usage policy.Usage
+	for _, other := range rules {
+		if other.GetNamespace() == rule.GetNamespace() && other.GetName() == rule.GetName() {
+			continue
+		}
+		if (other.RuleSpec().Backend == monitoringv1.BackendLoki) != isLoki {
+			continue
+		}
+		usage.Groups += other.RuleStatus().Groups
+		usage.Rules += other.RuleStatus().Rules
+	}
+	return usage, nil
+}
+
+func quotaExceededCondition(exceeded []string) metav1.Condition {
+	if len(exceeded) > 0 {
+		return metav1.Condition{
+			Type:    monitoringv1.ConditionQuotaExceeded,
+			Status:  metav1.ConditionTrue,
+			Reason:  "QuotaExceeded",
+			Message: truncate(strings.Join(exceeded, "; "), maxEventMessageLength),
+		}
+	}
+	return metav1.Condition{
+		Type:   monitoringv1.ConditionQuotaExceeded,
+		Status: metav1.ConditionFalse,
+		Reason: "WithinQuota",
+	}
+}
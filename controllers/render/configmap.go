@@ -0,0 +1,107 @@
+package render
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+)
+
+// configMapPrefix is prepended to the name of a ConfigMap in its Cortex namespace. It contains
+// an underscore, which object names may not, so it cannot clash with the Cortex namespace of a PrometheusRule.
+const configMapPrefix = "configmap_"
+
+// ruleFile is a Prometheus rule file, as loaded by Prometheus through rule_files.
+type ruleFile struct {
+	Groups []v1.RuleGroup `json:"groups"`
+}
+
+// ConfigMapCortexNamespace returns the Cortex namespace the rule groups of cm are written to,
+// {namespace}--configmap_{name}.
+func ConfigMapCortexNamespace(cm *corev1.ConfigMap) string {
+	return cm.Namespace + namespaceSeparator + configMapPrefix + cm.Name
+}
+
+// IsConfigMapCortexNamespace reports whether cortexNamespace is named like the Cortex namespace of a ConfigMap.
+func IsConfigMapCortexNamespace(cortexNamespace string) bool {
+	parts := strings.SplitN(cortexNamespace, namespaceSeparator, 2)
+	return len(parts) == 2 && strings.HasPrefix(parts[1], configMapPrefix)
+}
+
+// NewConfigMapRule returns the PrometheusRule generated for cm, of the same name, without rule groups.
+// It keeps the annotations of cm, records the resource version of cm and, unless cm sets another one,
+// names the Cortex namespace of cm in the Cortex namespace annotation.
+func NewConfigMapRule(cm *corev1.ConfigMap) v1.PrometheusRule {
+	annotations := make(map[string]string, len(cm.Annotations)+2)
+	for k, v := range cm.Annotations {
+		if k != corev1.LastAppliedConfigAnnotation {
+			annotations[k] = v
+		}
+	}
+	if annotations[v1.CortexNamespaceAnnotation] == "" {
+		annotations[v1.CortexNamespaceAnnotation] = ConfigMapCortexNamespace(cm)
+	}
+	annotations[v1.ConfigMapVersionAnnotation] = cm.ResourceVersion
+
+	return v1.PrometheusRule{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        cm.Name,
+			Namespace:   cm.Namespace,
+			Annotations: annotations,
+		},
+	}
+}
+
+// ParseRuleFiles parses every value of data as a Prometheus rule file and returns
+// their rule groups, in the order of the keys.
+func ParseRuleFiles(data map[string]string) ([]v1.RuleGroup, error) {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var groups []v1.RuleGroup
+	seen := map[string]string{}
+	for _, key := range keys {
+		var f ruleFile
+		if err := yaml.Unmarshal([]byte(data[key]), &f); err != nil {
+			return nil, fmt.Errorf("unable to parse rule file %s: %w", key, err)
+		}
+
+		for _, g := range f.Groups {
+			if err := validateRuleGroup(g); err != nil {
+				return nil, fmt.Errorf("invalid rule file %s: %w", key, err)
+			}
+			if other, ok := seen[g.Name]; ok {
+				return nil, fmt.Errorf("rule group %q of %s is already defined in %s", g.Name, key, other)
+			}
+			seen[g.Name] = key
+			groups = append(groups, g)
+		}
+	}
+	return groups, nil
+}
+
+// validateRuleGroup checks the fields Prometheus requires in a rule file.
+func validateRuleGroup(g v1.RuleGroup) error {
+	if g.Name == "" {
+		return fmt.Errorf("rule group has no name")
+	}
+	for i, rule := range g.Rules {
+		switch {
+		case rule.Alert == "" && rule.Record == "":
+			return fmt.Errorf("rule %d of group %q has neither alert nor record set", i, g.Name)
+		case rule.Alert != "" && rule.Record != "":
+			return fmt.Errorf("rule %d of group %q has both alert and record set", i, g.Name)
+		case rule.Expr.String() == "":
+			return fmt.Errorf("rule %d of group %q has no expr", i, g.Name)
+		}
+	}
+	return nil
+}
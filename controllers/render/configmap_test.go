@@ -0,0 +1,76 @@
+package render
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+)
+
+var _ = Describe("ConfigMap rule files", func() {
+	It("Should parse the rule groups of every key in key order", func() {
+		groups, err := ParseRuleFiles(map[string]string{
+			"b.rules.yaml": `
+groups:
+- name: b
+  rules:
+  - record: job:up:sum
+    expr: sum by (job) (up)
+`,
+			"a.rules.yaml": `
+groups:
+- name: a
+  interval: 1m
+  rules:
+  - alert: Down
+    expr: up == 0
+    for: 5m
+    labels:
+      severity: page
+`,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(groups).To(HaveLen(2))
+		Expect(groups[0].Name).To(Equal("a"))
+		Expect(groups[0].Interval).To(Equal("1m"))
+		Expect(groups[0].Rules[0].Alert).To(Equal("Down"))
+		Expect(groups[0].Rules[0].Expr.String()).To(Equal("up == 0"))
+		Expect(groups[0].Rules[0].Labels).To(HaveKeyWithValue("severity", "page"))
+		Expect(groups[1].Rules[0].Record).To(Equal("job:up:sum"))
+	})
+
+	It("Should reject invalid rule files", func() {
+		_, err := ParseRuleFiles(map[string]string{"a.yaml": "groups: [}"})
+		Expect(err).To(MatchError(ContainSubstring("unable to parse rule file a.yaml")))
+
+		_, err = ParseRuleFiles(map[string]string{"a.yaml": "groups:\n- name: a\n  rules:\n  - expr: up\n"})
+		Expect(err).To(MatchError(ContainSubstring("neither alert nor record")))
+
+		_, err = ParseRuleFiles(map[string]string{
+			"a.yaml": "groups:\n- name: a\n  rules: []\n",
+			"b.yaml": "groups:\n- name: a\n  rules: []\n",
+		})
+		Expect(err).To(MatchError(`rule group "a" of b.yaml is already defined in a.yaml`))
+	})
+
+	It("Should name the Cortex namespace after the ConfigMap", func() {
+		cm := corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team-a", ResourceVersion: "42",
+			Annotations: map[string]string{corev1.LastAppliedConfigAnnotation: "{}", v1.PausedAnnotation: "true"}}}
+		rule := NewConfigMapRule(&cm)
+		Expect(rule.Name).To(Equal("app"))
+		Expect(rule.Annotations).To(Equal(map[string]string{
+			v1.PausedAnnotation:           "true",
+			v1.CortexNamespaceAnnotation:  "team-a--configmap_app",
+			v1.ConfigMapVersionAnnotation: "42",
+		}))
+		Expect(Renderer{}.CortexNamespace(&rule)).To(Equal("team-a--configmap_app"))
+
+		other := v1.PrometheusRule{ObjectMeta: metav1.ObjectMeta{Name: "configmap-app", Namespace: "team-a"}}
+		Expect(Renderer{}.CortexNamespace(&other)).NotTo(Equal("team-a--configmap_app"))
+
+		Expect(IsConfigMapCortexNamespace(ConfigMapCortexNamespace(&cm))).To(BeTrue())
+		Expect(IsConfigMapCortexNamespace("team-a--configmap-app")).To(BeFalse())
+	})
+})
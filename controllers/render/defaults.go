@@ -0,0 +1,96 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/common/model"
+
+	v1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+)
+
+// Defaults are the values set on rule groups and rules that leave them out.
+type Defaults struct {
+	// Interval is the evaluation interval of groups without one.
+	Interval string
+	// For is the for duration of alerting rules without one.
+	For string
+	// Severity is the severity label of alerting rules without one.
+	Severity string
+	// LowercaseLabelKeys lowercases the label keys of all rules.
+	LowercaseLabelKeys bool
+}
+
+// Validate checks that the durations of d can be parsed.
+func (d Defaults) Validate() error {
+	if d.Interval != "" {
+		if _, err := model.ParseDuration(d.Interval); err != nil {
+			return fmt.Errorf("invalid default interval %q: %w", d.Interval, err)
+		}
+	}
+	if d.For != "" {
+		if _, err := model.ParseDuration(d.For); err != nil {
+			return fmt.Errorf("invalid default for %q: %w", d.For, err)
+		}
+	}
+	return nil
+}
+
+// Apply sets the defaults on the groups of spec and reports if anything changed.
+// Label keys are not lowercased if the lowercase key is set as well or other keys have the
+// same lowercase form, so no label is lost.
+func (d Defaults) Apply(spec *v1.PrometheusRuleSpec) bool {
+	changed := false
+	for i := range spec.Groups {
+		g := &spec.Groups[i]
+		if g.Interval == "" && d.Interval != "" {
+			g.Interval = d.Interval
+			changed = true
+		}
+
+		for j := range g.Rules {
+			r := &g.Rules[j]
+			if d.LowercaseLabelKeys && lowercaseKeys(r.Labels) {
+				changed = true
+			}
+			if r.Alert == "" {
+				continue
+			}
+
+			if r.For == "" && d.For != "" {
+				r.For = d.For
+				changed = true
+			}
+			if _, ok := r.Labels["severity"]; !ok && d.Severity != "" {
+				if r.Labels == nil {
+					r.Labels = map[string]string{}
+				}
+				r.Labels["severity"] = d.Severity
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// lowercaseKeys lowercases the keys of labels in place and reports if any key changed.
+// Keys whose lowercase form is shared with another key are kept, as neither of them may win.
+func lowercaseKeys(labels map[string]string) bool {
+	keys := map[string][]string{}
+	for key := range labels {
+		lower := strings.ToLower(key)
+		keys[lower] = append(keys[lower], key)
+	}
+
+	changed := false
+	for lower, same := range keys {
+		if len(same) > 1 || same[0] == lower {
+			continue
+		}
+
+		labels[lower] = labels[same[0]]
+		delete(labels, same[0])
+		changed = true
+	}
+	return changed
+}
@@ -0,0 +1,61 @@
+package render
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	v1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+)
+
+var _ = Describe("Applying defaults", func() {
+	defaults := Defaults{Interval: "1m", For: "5m", Severity: "warning", LowercaseLabelKeys: true}
+
+	It("Should fill in unset values", func() {
+		spec := v1.PrometheusRuleSpec{Groups: []v1.RuleGroup{{
+			Name: "example",
+			Rules: []v1.Rule{
+				{Alert: "HighErrorRate", Expr: intstr.FromString("rate(errors[5m]) > 1"), Labels: map[string]string{"Team": "payments"}},
+				{Record: "job:errors:rate5m", Expr: intstr.FromString("rate(errors[5m])")},
+			},
+		}}}
+
+		Expect(defaults.Apply(&spec)).To(BeTrue())
+		g := spec.Groups[0]
+		Expect(g.Interval).To(Equal("1m"))
+		Expect(g.Rules[0].For).To(Equal("5m"))
+		Expect(g.Rules[0].Labels).To(Equal(map[string]string{"team": "payments", "severity": "warning"}))
+		Expect(g.Rules[1].For).To(BeEmpty())
+		Expect(g.Rules[1].Labels).To(BeEmpty())
+	})
+
+	It("Should keep set values", func() {
+		spec := v1.PrometheusRuleSpec{Groups: []v1.RuleGroup{{
+			Name:     "example",
+			Interval: "30s",
+			Rules: []v1.Rule{
+				{Alert: "HighErrorRate", Expr: intstr.FromString("rate(errors[5m]) > 1"), For: "10m", Labels: map[string]string{"severity": "critical"}},
+			},
+		}}}
+
+		Expect(defaults.Apply(&spec)).To(BeFalse())
+		Expect(spec.Groups[0].Interval).To(Equal("30s"))
+		Expect(spec.Groups[0].Rules[0].For).To(Equal("10m"))
+		Expect(spec.Groups[0].Rules[0].Labels).To(Equal(map[string]string{"severity": "critical"}))
+	})
+
+	It("Should not lowercase keys that would overwrite a label", func() {
+		labels := map[string]string{"Team": "a", "team": "b"}
+		Expect(lowercaseKeys(labels)).To(BeFalse())
+		Expect(labels).To(Equal(map[string]string{"Team": "a", "team": "b"}))
+
+		labels = map[string]string{"Team": "a", "TEAM": "b", "Severity": "page"}
+		Expect(lowercaseKeys(labels)).To(BeTrue())
+		Expect(labels).To(Equal(map[string]string{"Team": "a", "TEAM": "b", "severity": "page"}))
+	})
+
+	It("Should reject invalid durations", func() {
+		Expect(Defaults{Interval: "1m", For: "5m"}.Validate()).To(Succeed())
+		Expect(Defaults{For: "five minutes"}.Validate()).NotTo(Succeed())
+	})
+})
@@ -0,0 +1,54 @@
+package render
+
+import (
+	"strings"
+
+	v1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+)
+
+// filterDisabled drops the disabled groups and rules of rule, and groups left without rules.
+// It returns the remaining groups and the skipped items as group or group/rule.
+func filterDisabled(rule v1.RuleObject, groups []v1.RuleGroup) (enabled []v1.RuleGroup, skipped []string) {
+	disabled := disabledGroups(rule)
+
+	for _, g := range groups {
+		if g.Disabled || disabled[g.Name] {
+			skipped = append(skipped, g.Name)
+			continue
+		}
+
+		rules := make([]v1.Rule, 0, len(g.Rules))
+		for _, r := range g.Rules {
+			if r.Disabled {
+				skipped = append(skipped, ruleName(g, r))
+				continue
+			}
+			rules = append(rules, r)
+		}
+
+		// Cortex does not accept empty groups.
+		if len(rules) == 0 {
+			continue
+		}
+
+		g.Rules = rules
+		enabled = append(enabled, g)
+	}
+	return enabled, skipped
+}
+
+// disabledGroups returns the groups listed in the disabled groups annotation of rule.
+func disabledGroups(rule v1.RuleObject) map[string]bool {
+	value, ok := rule.GetAnnotations()[v1.DisabledGroupsAnnotation]
+	if !ok {
+		return nil
+	}
+
+	groups := make(map[string]bool)
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			groups[name] = true
+		}
+	}
+	return groups
+}
@@ -0,0 +1,51 @@
+package render
+
+import (
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+// enforceMatcher makes every vector selector in expr match name="value".
+// Existing matchers on name are replaced, so a rule cannot widen its selection.
+// It returns the rewritten expression and whether it differs from the input.
+func enforceMatcher(expr, name, value string) (string, bool, error) {
+	e, err := parser.ParseExpr(expr)
+	if err != nil {
+		return "", false, err
+	}
+
+	changed := false
+	parser.Inspect(e, func(node parser.Node, _ []parser.Node) error {
+		vs, ok := node.(*parser.VectorSelector)
+		if !ok {
+			return nil
+		}
+
+		matchers := make([]*labels.Matcher, 0, len(vs.LabelMatchers)+1)
+		enforced := false
+		for _, m := range vs.LabelMatchers {
+			if m.Name != name {
+				matchers = append(matchers, m)
+				continue
+			}
+			if m.Type == labels.MatchEqual && m.Value == value && !enforced {
+				matchers = append(matchers, m)
+				enforced = true
+				continue
+			}
+			changed = true
+		}
+
+		if !enforced {
+			matchers = append(matchers, labels.MustNewMatcher(labels.MatchEqual, name, value))
+			changed = true
+		}
+		vs.LabelMatchers = matchers
+		return nil
+	})
+
+	if !changed {
+		return expr, false, nil
+	}
+	return e.String(), true, nil
+}
@@ -0,0 +1,62 @@
+package render
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/ghodss/yaml"
+
+	v1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+)
+
+// anyNamespace is the FederationPolicy key matching every namespace.
+const anyNamespace = "*"
+
+// FederationPolicy controls which tenants the rule groups of a Kubernetes namespace
+// may query through source_tenants.
+type FederationPolicy struct {
+	// SourceTenants maps namespaces to the tenants they may query. The key "*" applies to every namespace.
+	SourceTenants map[string][]string `json:"sourceTenants"`
+}
+
+// LoadFederationPolicy reads a FederationPolicy from a YAML file.
+func LoadFederationPolicy(path string) (*FederationPolicy, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var p FederationPolicy
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("unable to parse federation policy %s: %w", path, err)
+	}
+	return &p, nil
+}
+
+// Allowed reports whether rule groups in namespace may query tenant.
+func (p *FederationPolicy) Allowed(namespace, tenant string) bool {
+	if p == nil {
+		return false
+	}
+
+	for _, ns := range []string{namespace, anyNamespace} {
+		for _, t := range p.SourceTenants[ns] {
+			if t == tenant {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkSourceTenants verifies that a rule group in namespace only queries own,
+// the tenant the rules are written to, or tenants allowed by the federation policy.
+func (r Renderer) checkSourceTenants(namespace, own string, g v1.RuleGroup) error {
+	for _, tenant := range g.SourceTenants {
+		if tenant == own || r.Federation.Allowed(namespace, tenant) {
+			continue
+		}
+		return fmt.Errorf("rule group %q in namespace %q is not allowed to query source tenant %q", g.Name, namespace, tenant)
+	}
+	return nil
+}
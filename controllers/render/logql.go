@@ -0,0 +1,156 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// logQLMatcher is a label matcher of a LogQL stream selector.
+var logQLMatcher = regexp.MustCompile("^([a-zA-Z_][a-zA-Z0-9_]*)\\s*(=~|!~|!=|=)\\s*(\"(?:[^\"\\\\]|\\\\.)*\"|`[^`]*`)$")
+
+// streamSelector is a stream selector of a LogQL expression.
+type streamSelector struct {
+	// start and end are the positions of the opening and closing brace.
+	start, end int
+	matchers   []string
+}
+
+// parseLogQL finds the stream selectors of a LogQL expression. Without a full LogQL parser it only
+// checks that strings are terminated, brackets are balanced and every stream selector consists of
+// label matchers. Braces only occur in stream selectors outside of strings.
+func parseLogQL(expr string) ([]streamSelector, error) {
+	var selectors []streamSelector
+	var brackets []byte
+	start := -1
+
+	for i := 0; i < len(expr); i++ {
+		switch c := expr[i]; c {
+		case '"', '`':
+			end, err := skipString(expr, i)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+		case '(', '[':
+			brackets = append(brackets, c)
+		case '{':
+			if start >= 0 {
+				return nil, fmt.Errorf("unexpected '{' at position %d", i)
+			}
+			start = i
+		case ')', ']':
+			open := byte('(')
+			if c == ']' {
+				open = '['
+			}
+			if len(brackets) == 0 || brackets[len(brackets)-1] != open || start >= 0 {
+				return nil, fmt.Errorf("unexpected '%c' at position %d", c, i)
+			}
+			brackets = brackets[:len(brackets)-1]
+		case '}':
+			if start < 0 {
+				return nil, fmt.Errorf("unexpected '}' at position %d", i)
+			}
+			matchers, err := parseStreamMatchers(expr[start+1 : i])
+			if err != nil {
+				return nil, err
+			}
+			selectors = append(selectors, streamSelector{start: start, end: i, matchers: matchers})
+			start = -1
+		}
+	}
+
+	if start >= 0 || len(brackets) > 0 {
+		return nil, errors.New("unclosed bracket")
+	}
+	if len(selectors) == 0 {
+		return nil, errors.New("no stream selector")
+	}
+	return selectors, nil
+}
+
+// parseStreamMatchers splits the content of a stream selector into its label matchers.
+func parseStreamMatchers(s string) ([]string, error) {
+	var matchers []string
+	begin := 0
+	for i := 0; i <= len(s); i++ {
+		if i < len(s) && (s[i] == '"' || s[i] == '`') {
+			end, err := skipString(s, i)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+			continue
+		}
+		if i < len(s) && s[i] != ',' {
+			continue
+		}
+
+		m := strings.TrimSpace(s[begin:i])
+		if !logQLMatcher.MatchString(m) {
+			return nil, fmt.Errorf("invalid label matcher %q in stream selector {%s}", m, s)
+		}
+		matchers = append(matchers, m)
+		begin = i + 1
+	}
+	return matchers, nil
+}
+
+// skipString returns the position of the quote closing the string that starts at i.
+func skipString(s string, i int) (int, error) {
+	quote := s[i]
+	for j := i + 1; j < len(s); j++ {
+		switch {
+		case s[j] == '\\' && quote == '"':
+			j++
+		case s[j] == quote:
+			return j, nil
+		}
+	}
+	return 0, fmt.Errorf("unterminated string at position %d", i)
+}
+
+// enforceLogQLMatcher makes every stream selector in expr match name="value",
+// like enforceMatcher does for PromQL vector selectors.
+func enforceLogQLMatcher(expr, name, value string) (string, bool, error) {
+	selectors, err := parseLogQL(expr)
+	if err != nil {
+		return "", false, err
+	}
+
+	enforced := name + "=" + strconv.Quote(value)
+	changed := false
+	var b strings.Builder
+	last := 0
+	for _, sel := range selectors {
+		matchers := make([]string, 0, len(sel.matchers)+1)
+		onName := 0
+		for _, m := range sel.matchers {
+			if logQLMatcher.FindStringSubmatch(m)[1] == name {
+				onName++
+				if m != enforced {
+					changed = true
+				}
+				continue
+			}
+			matchers = append(matchers, m)
+		}
+		if onName != 1 {
+			changed = true
+		}
+		matchers = append(matchers, enforced)
+
+		b.WriteString(expr[last:sel.start])
+		b.WriteString("{" + strings.Join(matchers, ", ") + "}")
+		last = sel.end + 1
+	}
+	b.WriteString(expr[last:])
+
+	if !changed {
+		return expr, false, nil
+	}
+	return b.String(), true, nil
+}
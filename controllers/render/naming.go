@@ -0,0 +1,114 @@
+package render
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	v1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+)
+
+const (
+	// namespaceSeparator separates the Kubernetes namespace and name in a Cortex namespace.
+	namespaceSeparator = "--"
+	// clusterNamespace takes the place of the Kubernetes namespace for cluster-scoped rules.
+	// It is not a valid Kubernetes namespace, so it cannot clash with namespaced rules.
+	clusterNamespace = "_cluster"
+)
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// CortexNamespaceOverride allows the rules of a Kubernetes namespace to write to the Cortex namespaces
+// matching a pattern through the Cortex namespace annotation.
+type CortexNamespaceOverride struct {
+	// Namespace is the Kubernetes namespace of the rules, or _cluster for cluster-scoped rules.
+	Namespace string
+	// Pattern is a shell pattern of Cortex namespaces, as matched by path.Match.
+	Pattern string
+}
+
+// CortexNamespace returns the Cortex namespace the rule groups of rule are written to by the naming scheme.
+// It ignores the Cortex namespace annotation, use Renderer.CortexNamespace to honor it.
+func CortexNamespace(rule v1.RuleObject) string {
+	return ownerNamespace(rule) + namespaceSeparator + rule.GetName()
+}
+
+// CortexNamespace returns the Cortex namespace the rule groups of rule are written to: the value of the
+// Cortex namespace annotation if it is permitted, the naming scheme otherwise.
+// The annotation may name any Cortex namespace of the Kubernetes namespace of rule, others only if
+// an override allows it, so rules cannot take over the rules of other namespaces. Names after the
+// separator must not contain another one, as team--x--alerts may belong to the namespace team--x.
+func (r Renderer) CortexNamespace(rule v1.RuleObject) (string, error) {
+	cortexNamespace := rule.GetAnnotations()[v1.CortexNamespaceAnnotation]
+	if cortexNamespace == "" {
+		return CortexNamespace(rule), nil
+	}
+
+	owner := ownerNamespace(rule)
+	prefix := owner + namespaceSeparator
+	if strings.HasPrefix(cortexNamespace, prefix) {
+		if name := cortexNamespace[len(prefix):]; name != "" && !strings.Contains(name, namespaceSeparator) {
+			return cortexNamespace, nil
+		}
+	}
+	for _, o := range r.CortexNamespaceOverrides {
+		if o.Namespace != owner {
+			continue
+		}
+		if ok, _ := path.Match(o.Pattern, cortexNamespace); ok {
+			return cortexNamespace, nil
+		}
+	}
+	return "", fmt.Errorf("Cortex namespace %q set by %s is not allowed for namespace %s", cortexNamespace, v1.CortexNamespaceAnnotation, owner)
+}
+
+// ownerNamespace returns the Kubernetes namespace of rule as used in Cortex namespaces.
+func ownerNamespace(rule v1.RuleObject) string {
+	if rule.GetNamespace() == "" {
+		return clusterNamespace
+	}
+	return rule.GetNamespace()
+}
+
+// ParseCortexNamespace reverses CortexNamespace. It reports false if cortexNamespace
+// does not follow the naming scheme or does not contain valid Kubernetes names.
+func ParseCortexNamespace(cortexNamespace string) (namespace, name string, ok bool) {
+	parts := strings.SplitN(cortexNamespace, namespaceSeparator, 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	if len(validation.IsDNS1123Label(parts[0])) > 0 || len(validation.IsDNS1123Subdomain(parts[1])) > 0 {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// ParseClusterCortexNamespace reverses CortexNamespace for cluster-scoped rules. It reports false if
+// cortexNamespace is not the Cortex namespace of a ClusterPrometheusRule by the naming scheme.
+func ParseClusterCortexNamespace(cortexNamespace string) (name string, ok bool) {
+	prefix := clusterNamespace + namespaceSeparator
+	if !strings.HasPrefix(cortexNamespace, prefix) {
+		return "", false
+	}
+	name = cortexNamespace[len(prefix):]
+	if len(validation.IsDNS1123Subdomain(name)) > 0 {
+		return "", false
+	}
+	return name, true
+}
+
+// SanitizeName turns s into a valid Kubernetes object name.
+func SanitizeName(s string) string {
+	name := invalidNameChars.ReplaceAllString(strings.ToLower(s), "-")
+	if len(name) > validation.DNS1123SubdomainMaxLength {
+		name = name[:validation.DNS1123SubdomainMaxLength]
+	}
+	name = strings.Trim(name, "-.")
+	if name == "" {
+		return "imported"
+	}
+	return name
+}
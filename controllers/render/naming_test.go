@@ -0,0 +1,97 @@
+package render
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+)
+
+var _ = Describe("Naming", func() {
+	It("Should map PrometheusRules to Cortex namespaces and back", func() {
+		rule := newRule()
+		Expect(CortexNamespace(&rule)).To(Equal("team-a--example"))
+
+		namespace, name, ok := ParseCortexNamespace("team-a--example--v2")
+		Expect(ok).To(BeTrue())
+		Expect(namespace).To(Equal("team-a"))
+		Expect(name).To(Equal("example--v2"))
+
+		_, _, ok = ParseCortexNamespace("Team_A/alerts")
+		Expect(ok).To(BeFalse())
+		Expect(SanitizeName("Team_A/alerts")).To(Equal("team-a-alerts"))
+	})
+
+	It("Should use the Cortex namespace annotation within the namespace of the rule", func() {
+		rule := newRule()
+		rule.Annotations = map[string]string{v1.CortexNamespaceAnnotation: "team-a--legacy"}
+		Expect(CortexNamespace(&rule)).To(Equal("team-a--example"))
+
+		cortexNamespace, err := Renderer{}.CortexNamespace(&rule)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cortexNamespace).To(Equal("team-a--legacy"))
+	})
+
+	It("Should reject Cortex namespace annotations outside of the namespace of the rule", func() {
+		for _, ns := range []string{"legacy", "team-b--example", "_cluster--node", "team-a--"} {
+			rule := newRule()
+			rule.Annotations = map[string]string{v1.CortexNamespaceAnnotation: ns}
+			_, err := Renderer{}.CortexNamespace(&rule)
+			Expect(err).To(HaveOccurred(), ns)
+		}
+	})
+
+	It("Should reject Cortex namespace annotations of nested namespaces", func() {
+		// team-a--x--alerts is the Cortex namespace of the rule alerts in the namespace team-a--x.
+		rule := newRule()
+		rule.Annotations = map[string]string{v1.CortexNamespaceAnnotation: "team-a--x--alerts"}
+		_, err := Renderer{}.CortexNamespace(&rule)
+		Expect(err).To(HaveOccurred())
+
+		nested := newRule()
+		nested.Namespace = "team-a--x"
+		nested.Name = "alerts"
+		Expect(CortexNamespace(&nested)).To(Equal("team-a--x--alerts"))
+	})
+
+	It("Should allow Cortex namespace annotations permitted by an override", func() {
+		r := Renderer{CortexNamespaceOverrides: []CortexNamespaceOverride{
+			{Namespace: "team-a", Pattern: "legacy-*"},
+			{Namespace: "_cluster", Pattern: "platform"},
+		}}
+
+		rule := newRule()
+		rule.Annotations = map[string]string{v1.CortexNamespaceAnnotation: "legacy-alerts"}
+		cortexNamespace, err := r.CortexNamespace(&rule)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cortexNamespace).To(Equal("legacy-alerts"))
+
+		rule.Annotations[v1.CortexNamespaceAnnotation] = "platform"
+		_, err = r.CortexNamespace(&rule)
+		Expect(err).To(HaveOccurred())
+
+		clusterRule := v1.ClusterPrometheusRule{ObjectMeta: metav1.ObjectMeta{
+			Name:        "node",
+			Annotations: map[string]string{v1.CortexNamespaceAnnotation: "platform"},
+		}}
+		cortexNamespace, err = r.CortexNamespace(&clusterRule)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cortexNamespace).To(Equal("platform"))
+	})
+
+	It("Should keep cluster-scoped rules apart from namespaced ones", func() {
+		rule := v1.ClusterPrometheusRule{ObjectMeta: metav1.ObjectMeta{Name: "node"}}
+		Expect(CortexNamespace(&rule)).To(Equal("_cluster--node"))
+
+		_, _, ok := ParseCortexNamespace("_cluster--node")
+		Expect(ok).To(BeFalse())
+
+		name, ok := ParseClusterCortexNamespace("_cluster--node")
+		Expect(ok).To(BeTrue())
+		Expect(name).To(Equal("node"))
+
+		_, ok = ParseClusterCortexNamespace("team-a--node")
+		Expect(ok).To(BeFalse())
+	})
+})
@@ -0,0 +1,169 @@
+package render
+
+import (
+	"fmt"
+	"os"
+
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	v1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+)
+
+// Renderer prepares the rule groups of a PrometheusRule before they are sent to Cortex.
+type Renderer struct {
+	// Labels are set on every alerting and recording rule, overriding labels of the same name.
+	// Values may reference ${namespace}, ${name}, ${cortex_namespace} and ${tenant}.
+	Labels map[string]string
+	// Tenant is the Cortex tenant the rules are written to.
+	Tenant string
+	// LokiTenant is the Loki tenant the rules of the Loki backend are written to.
+	LokiTenant string
+	// EnforceNamespaceLabel, if set, is the label every vector selector of a rule expression
+	// is restricted to, with the namespace of the PrometheusRule as value.
+	EnforceNamespaceLabel string
+	// Federation controls which tenants federated rule groups may query.
+	// Without a policy, rule groups may only query the tenant they are written to.
+	Federation *FederationPolicy
+	// CortexNamespaceOverrides allow the Cortex namespace annotation to name Cortex namespaces outside of
+	// the Kubernetes namespace of a rule.
+	CortexNamespaceOverrides []CortexNamespaceOverride
+}
+
+// Result contains the rendered rule groups of a PrometheusRule.
+type Result struct {
+	Groups []v1.RuleGroup
+	// Rewritten lists the rules, as group/rule, whose expression was rewritten.
+	Rewritten []string
+	// Skipped lists the disabled groups and rules as group or group/rule.
+	Skipped []string
+}
+
+// Render returns the rule groups of rule as they should be sent to Cortex, or to Loki
+// if that is the backend of rule.
+// The passed rule is not modified.
+func (r Renderer) Render(rule v1.RuleObject, cortexNamespace string) (*Result, error) {
+	spec := rule.RuleSpec().DeepCopy()
+	result := &Result{}
+
+	groups, skipped := filterDisabled(rule, spec.Groups)
+	result.Skipped = skipped
+
+	labels := r.expandLabels(rule, cortexNamespace)
+	for i := range groups {
+		g := &groups[i]
+		if err := r.checkSourceTenants(rule.GetNamespace(), r.TenantOf(rule), *g); err != nil {
+			return nil, err
+		}
+
+		injectLabels(g.Rules, labels)
+
+		if spec.Backend == v1.BackendLoki {
+			if err := validateLogQL(*g); err != nil {
+				return nil, err
+			}
+		}
+
+		// Cluster-scoped rules have no namespace to restrict them to.
+		if r.EnforceNamespaceLabel != "" && rule.GetNamespace() != "" {
+			rewritten, err := r.enforceNamespace(g, spec.Backend, rule.GetNamespace())
+			if err != nil {
+				return nil, err
+			}
+			result.Rewritten = append(result.Rewritten, rewritten...)
+		}
+	}
+
+	result.Groups = groups
+	return result, nil
+}
+
+// TenantOf returns the tenant the rules of rule are written to, depending on its backend.
+func (r Renderer) TenantOf(rule v1.RuleObject) string {
+	if rule.RuleSpec().Backend == v1.BackendLoki {
+		return r.LokiTenant
+	}
+	return r.Tenant
+}
+
+// expandLabels resolves the variables used in the configured label values for rule.
+func (r Renderer) expandLabels(rule v1.RuleObject, cortexNamespace string) map[string]string {
+	if len(r.Labels) == 0 {
+		return nil
+	}
+
+	vars := map[string]string{
+		"namespace":        rule.GetNamespace(),
+		"name":             rule.GetName(),
+		"cortex_namespace": cortexNamespace,
+		"tenant":           r.TenantOf(rule),
+	}
+
+	labels := make(map[string]string, len(r.Labels))
+	for k, v := range r.Labels {
+		labels[k] = os.Expand(v, func(key string) string {
+			return vars[key]
+		})
+	}
+	return labels
+}
+
+// enforceNamespace restricts the expressions of all rules in g to namespace
+// and returns the rules that were rewritten.
+func (r Renderer) enforceNamespace(g *v1.RuleGroup, backend v1.Backend, namespace string) ([]string, error) {
+	enforce := enforceMatcher
+	if backend == v1.BackendLoki {
+		enforce = enforceLogQLMatcher
+	}
+
+	var rewritten []string
+	for i := range g.Rules {
+		rule := &g.Rules[i]
+		if rule.Expr.Type != intstr.String {
+			continue
+		}
+
+		expr, changed, err := enforce(rule.Expr.StrVal, r.EnforceNamespaceLabel, namespace)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse expression of %s: %w", ruleName(*g, *rule), err)
+		}
+		if changed {
+			rule.Expr = intstr.FromString(expr)
+			rewritten = append(rewritten, ruleName(*g, *rule))
+		}
+	}
+	return rewritten, nil
+}
+
+// validateLogQL checks that the expressions of all rules in g look like LogQL.
+func validateLogQL(g v1.RuleGroup) error {
+	for _, rule := range g.Rules {
+		if _, err := parseLogQL(rule.Expr.String()); err != nil {
+			return fmt.Errorf("invalid LogQL expression of %s: %w", ruleName(g, rule), err)
+		}
+	}
+	return nil
+}
+
+// injectLabels sets labels on every rule.
+func injectLabels(rules []v1.Rule, labels map[string]string) {
+	if len(labels) == 0 {
+		return
+	}
+
+	for i := range rules {
+		if rules[i].Labels == nil {
+			rules[i].Labels = make(map[string]string, len(labels))
+		}
+		for k, v := range labels {
+			rules[i].Labels[k] = v
+		}
+	}
+}
+
+// ruleName identifies rule within its group for status reporting.
+func ruleName(g v1.RuleGroup, rule v1.Rule) string {
+	if rule.Alert != "" {
+		return g.Name + "/" + rule.Alert
+	}
+	return g.Name + "/" + rule.Record
+}
@@ -0,0 +1,177 @@
+package render
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	v1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+)
+
+func newRule() v1.PrometheusRule {
+	return v1.PrometheusRule{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example",
+			Namespace: "team-a",
+		},
+		Spec: v1.PrometheusRuleSpec{
+			Groups: []v1.RuleGroup{
+				{
+					Name: "example.rules",
+					Rules: []v1.Rule{
+						{
+							Alert:  "ExampleAlert",
+							Expr:   intstr.FromString(`up{job="api"} == 0`),
+							Labels: map[string]string{"severity": "page", "cluster": "spoofed"},
+						},
+						{
+							Record: "job:up:sum",
+							Expr:   intstr.FromString("sum by (job) (up)"),
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+var _ = Describe("Renderer", func() {
+	Context("When labels are configured", func() {
+		It("Should inject expanded labels into every rule without modifying the PrometheusRule", func() {
+			r := Renderer{
+				Labels: map[string]string{
+					"cluster":        "prod",
+					"k8s_namespace":  "${namespace}",
+					"prometheusrule": "${name}",
+				},
+			}
+			rule := newRule()
+
+			result, err := r.Render(&rule, "team-a--example")
+			Expect(err).NotTo(HaveOccurred())
+			groups := result.Groups
+			Expect(groups[0].Rules[0].Labels).To(Equal(map[string]string{
+				"severity":       "page",
+				"cluster":        "prod",
+				"k8s_namespace":  "team-a",
+				"prometheusrule": "example",
+			}))
+			Expect(groups[0].Rules[1].Labels).To(HaveKeyWithValue("k8s_namespace", "team-a"))
+			Expect(rule.Spec.Groups[0].Rules[0].Labels).To(HaveKeyWithValue("cluster", "spoofed"))
+			Expect(rule.Spec.Groups[0].Rules[1].Labels).To(BeNil())
+		})
+	})
+	Context("When namespace enforcement is enabled", func() {
+		It("Should restrict every vector selector to the namespace", func() {
+			r := Renderer{EnforceNamespaceLabel: "namespace"}
+			rule := newRule()
+			rule.Spec.Groups[0].Rules[1].Expr = intstr.FromString(`sum by (job) (up{namespace="team-a"})`)
+
+			result, err := r.Render(&rule, "team-a--example")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Groups[0].Rules[0].Expr.StrVal).To(Equal(`up{job="api",namespace="team-a"} == 0`))
+			Expect(result.Groups[0].Rules[1].Expr.StrVal).To(Equal(`sum by (job) (up{namespace="team-a"})`))
+			Expect(result.Rewritten).To(Equal([]string{"example.rules/ExampleAlert"}))
+		})
+
+		It("Should replace matchers selecting other namespaces", func() {
+			expr, changed, err := enforceMatcher(`rate(http_requests_total{namespace=~".+"}[5m]) / on() group_left up{namespace="team-b"}`, "namespace", "team-a")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changed).To(BeTrue())
+			Expect(expr).To(Equal(`rate(http_requests_total{namespace="team-a"}[5m]) / on() group_left() up{namespace="team-a"}`))
+		})
+
+		It("Should fail on invalid expressions", func() {
+			r := Renderer{EnforceNamespaceLabel: "namespace"}
+			rule := newRule()
+			rule.Spec.Groups[0].Rules[0].Expr = intstr.FromString("sum(")
+
+			_, err := r.Render(&rule, "team-a--example")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("When a rule group is federated", func() {
+		It("Should only allow source tenants permitted by the federation policy", func() {
+			r := Renderer{
+				Tenant: "team-a",
+				Federation: &FederationPolicy{SourceTenants: map[string][]string{
+					"team-a": {"team-b"},
+					"*":      {"shared"},
+				}},
+			}
+			rule := newRule()
+
+			rule.Spec.Groups[0].SourceTenants = []string{"team-a", "team-b", "shared"}
+			_, err := r.Render(&rule, "team-a--example")
+			Expect(err).NotTo(HaveOccurred())
+
+			rule.Spec.Groups[0].SourceTenants = []string{"team-c"}
+			_, err = r.Render(&rule, "team-a--example")
+			Expect(err).To(MatchError(ContainSubstring(`source tenant "team-c"`)))
+		})
+	})
+	Context("When groups or rules are disabled", func() {
+		It("Should omit them and report them as skipped", func() {
+			rule := newRule()
+			rule.Annotations = map[string]string{v1.DisabledGroupsAnnotation: "annotated.rules, unknown.rules"}
+			rule.Spec.Groups[0].Rules[0].Disabled = true
+			rule.Spec.Groups = append(rule.Spec.Groups,
+				v1.RuleGroup{Name: "annotated.rules", Rules: rule.Spec.Groups[0].Rules},
+				v1.RuleGroup{Name: "disabled.rules", Disabled: true, Rules: rule.Spec.Groups[0].Rules},
+			)
+
+			result, err := Renderer{}.Render(&rule, "team-a--example")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Groups).To(HaveLen(1))
+			Expect(result.Groups[0].Rules).To(HaveLen(1))
+			Expect(result.Groups[0].Rules[0].Record).To(Equal("job:up:sum"))
+			Expect(result.Skipped).To(Equal([]string{"example.rules/ExampleAlert", "annotated.rules", "disabled.rules"}))
+		})
+	})
+	Context("When the backend is Loki", func() {
+		newLokiRule := func(expr string) *v1.PrometheusRule {
+			rule := newRule()
+			rule.Spec.Backend = v1.BackendLoki
+			rule.Spec.Groups[0].Rules = []v1.Rule{{Alert: "HighErrorRate", Expr: intstr.FromString(expr)}}
+			return &rule
+		}
+
+		It("Should accept LogQL expressions", func() {
+			_, err := Renderer{}.Render(newLokiRule(`sum(rate({app="api"} |= "error" | json [5m])) by (pod) > 10`), "team-a--example")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should reject expressions that are not LogQL", func() {
+			for _, expr := range []string{`up == 0`, `rate({app="api"}[5m]`, `{app}`, `{app="api} |= "x"`} {
+				_, err := Renderer{}.Render(newLokiRule(expr), "team-a--example")
+				Expect(err).To(MatchError(ContainSubstring("invalid LogQL expression")), expr)
+			}
+		})
+
+		It("Should expand ${tenant} to the Loki tenant", func() {
+			r := Renderer{Labels: map[string]string{"tenant": "${tenant}"}, Tenant: "cortex-a", LokiTenant: "loki-a"}
+			rule := newLokiRule(`count_over_time({app="api"}[5m]) > 10`)
+			rule.Spec.Groups[0].SourceTenants = []string{"loki-a"}
+
+			result, err := r.Render(rule, "team-a--example")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Groups[0].Rules[0].Labels).To(HaveKeyWithValue("tenant", "loki-a"))
+		})
+
+		It("Should restrict every stream selector to the namespace", func() {
+			r := Renderer{EnforceNamespaceLabel: "namespace"}
+			rule := newLokiRule(`count_over_time({app="api", namespace=~".+"} |= "{namespace}" [5m]) / count_over_time({app="api"}[5m])`)
+
+			result, err := r.Render(rule, "team-a--example")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Groups[0].Rules[0].Expr.StrVal).To(Equal(
+				`count_over_time({app="api", namespace="team-a"} |= "{namespace}" [5m]) / count_over_time({app="api", namespace="team-a"}[5m])`))
+			Expect(result.Rewritten).To(Equal([]string{"example.rules/HighErrorRate"}))
+
+			_, changed, err := enforceLogQLMatcher(`{namespace="team-a"}`, "namespace", "team-a")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changed).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,46 @@
+package render
+
+import (
+	"fmt"
+
+	v1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+)
+
+// SplitGroups splits the groups with more than maxRules rules into groups of at most maxRules
+// rules, named after the original group with the suffixes -1, -2 and so on. The rules keep
+// their order, so the split is the same for the same rules.
+// Groups that fit are returned unchanged. maxRules 0 disables splitting.
+func SplitGroups(groups []v1.RuleGroup, maxRules int) ([]v1.RuleGroup, error) {
+	if maxRules <= 0 {
+		return groups, nil
+	}
+
+	names := make(map[string]bool, len(groups))
+	for _, g := range groups {
+		names[g.Name] = true
+	}
+
+	split := make([]v1.RuleGroup, 0, len(groups))
+	for _, g := range groups {
+		if len(g.Rules) <= maxRules {
+			split = append(split, g)
+			continue
+		}
+
+		for i, n := 0, 1; i < len(g.Rules); i, n = i+maxRules, n+1 {
+			end := i + maxRules
+			if end > len(g.Rules) {
+				end = len(g.Rules)
+			}
+
+			part := g
+			part.Name = fmt.Sprintf("%s-%d", g.Name, n)
+			part.Rules = g.Rules[i:end]
+			if names[part.Name] {
+				return nil, fmt.Errorf("rule group %q cannot be split, %q is already defined", g.Name, part.Name)
+			}
+			split = append(split, part)
+		}
+	}
+	return split, nil
+}
@@ -0,0 +1,47 @@
+package render
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	v1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+)
+
+func newGroup(name string, rules int) v1.RuleGroup {
+	g := v1.RuleGroup{Name: name, Interval: "1m"}
+	for i := 0; i < rules; i++ {
+		g.Rules = append(g.Rules, v1.Rule{Record: fmt.Sprintf("rule_%d", i), Expr: intstr.FromString("vector(1)")})
+	}
+	return g
+}
+
+var _ = Describe("Splitting groups", func() {
+	It("Should split oversized groups into numbered groups", func() {
+		groups, err := SplitGroups([]v1.RuleGroup{newGroup("small", 2), newGroup("mixin", 5)}, 2)
+		Expect(err).NotTo(HaveOccurred())
+
+		var names []string
+		for _, g := range groups {
+			names = append(names, g.Name)
+			Expect(g.Interval).To(Equal("1m"))
+		}
+		Expect(names).To(Equal([]string{"small", "mixin-1", "mixin-2", "mixin-3"}))
+		Expect(groups[1].Rules[0].Record).To(Equal("rule_0"))
+		Expect(groups[3].Rules).To(HaveLen(1))
+		Expect(groups[3].Rules[0].Record).To(Equal("rule_4"))
+	})
+
+	It("Should keep groups unchanged without limit", func() {
+		groups, err := SplitGroups([]v1.RuleGroup{newGroup("mixin", 5)}, 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(groups).To(HaveLen(1))
+	})
+
+	It("Should refuse to overwrite an existing group", func() {
+		_, err := SplitGroups([]v1.RuleGroup{newGroup("mixin", 3), newGroup("mixin-2", 1)}, 2)
+		Expect(err).To(MatchError(`rule group "mixin" cannot be split, "mixin-2" is already defined`))
+	})
+})
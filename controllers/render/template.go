@@ -0,0 +1,164 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	v1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+)
+
+// The delimiters of rule templates differ from the Go template defaults,
+// which are used by Cortex for alert annotations.
+const (
+	leftDelim  = "[["
+	rightDelim = "]]"
+)
+
+// TemplateLookup returns the RuleTemplate with name from the namespace of the rule instantiating it.
+type TemplateLookup func(name string) (*v1.RuleTemplate, error)
+
+// ExpandTemplates returns a copy of rule whose groups include the instantiated rule groups
+// of its templates. rule itself is returned if it does not use templates.
+func ExpandTemplates(rule v1.RuleObject, lookup TemplateLookup) (v1.RuleObject, error) {
+	instances := rule.RuleSpec().Templates
+	if len(instances) == 0 {
+		return rule, nil
+	}
+	if rule.GetNamespace() == "" {
+		return nil, fmt.Errorf("cluster-scoped rules cannot use rule templates")
+	}
+
+	expanded := rule.DeepCopyObject().(v1.RuleObject)
+	spec := expanded.RuleSpec()
+
+	defined := map[string]bool{}
+	for _, g := range spec.Groups {
+		defined[g.Name] = true
+	}
+
+	for _, instance := range instances {
+		tmpl, err := lookup(instance.Name)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get rule template %s: %w", instance.Name, err)
+		}
+
+		groups, err := Instantiate(tmpl.Spec, instance.Values)
+		if err != nil {
+			return nil, fmt.Errorf("unable to instantiate rule template %s: %w", instance.Name, err)
+		}
+		for _, g := range groups {
+			if defined[g.Name] {
+				return nil, fmt.Errorf("rule group %q of template %s is already defined", g.Name, instance.Name)
+			}
+			defined[g.Name] = true
+			spec.Groups = append(spec.Groups, g)
+		}
+	}
+	spec.Templates = nil
+	return expanded, nil
+}
+
+// Instantiate returns the rule groups of spec with its parameters set to values.
+func Instantiate(spec v1.RuleTemplateSpec, values map[string]string) ([]v1.RuleGroup, error) {
+	params, err := templateParams(spec.Parameters, values)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &expander{params: params}
+	groups := make([]v1.RuleGroup, 0, len(spec.Groups))
+	for _, g := range spec.Groups {
+		g := *g.DeepCopy()
+		e.expand(&g.Name)
+		e.expand(&g.Interval)
+		e.expand(&g.QueryOffset)
+		e.expand(&g.EvaluationDelay)
+		for i := range g.SourceTenants {
+			e.expand(&g.SourceTenants[i])
+		}
+
+		for i := range g.Rules {
+			rule := &g.Rules[i]
+			e.expand(&rule.Record)
+			e.expand(&rule.Alert)
+			if rule.Expr.Type == intstr.String {
+				e.expand(&rule.Expr.StrVal)
+			}
+			e.expand(&rule.For)
+			e.expand(&rule.KeepFiringFor)
+			rule.Labels = e.expandMap(rule.Labels)
+			rule.Annotations = e.expandMap(rule.Annotations)
+		}
+
+		if e.err != nil {
+			return nil, fmt.Errorf("rule group %q: %w", g.Name, e.err)
+		}
+		groups = append(groups, g)
+	}
+	return groups, nil
+}
+
+// templateParams resolves the value of every declared parameter.
+func templateParams(declared []v1.TemplateParameter, values map[string]string) (map[string]string, error) {
+	params := make(map[string]string, len(declared))
+	for _, p := range declared {
+		if value, ok := values[p.Name]; ok {
+			params[p.Name] = value
+		} else if p.Default != nil {
+			params[p.Name] = *p.Default
+		} else {
+			return nil, fmt.Errorf("parameter %q is required", p.Name)
+		}
+	}
+
+	for name := range values {
+		if _, ok := params[name]; !ok {
+			return nil, fmt.Errorf("unknown parameter %q", name)
+		}
+	}
+	return params, nil
+}
+
+// expander expands templates with params and keeps the first error.
+type expander struct {
+	params map[string]string
+	err    error
+}
+
+// expand replaces s with its expansion.
+func (e *expander) expand(s *string) {
+	if e.err != nil || !strings.Contains(*s, leftDelim) {
+		return
+	}
+
+	t, err := template.New("").Delims(leftDelim, rightDelim).Option("missingkey=error").Parse(*s)
+	if err != nil {
+		e.err = err
+		return
+	}
+
+	var b strings.Builder
+	if err := t.Execute(&b, e.params); err != nil {
+		e.err = err
+		return
+	}
+	*s = b.String()
+}
+
+// expandMap returns a copy of m with expanded keys and values.
+func (e *expander) expandMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+
+	expanded := make(map[string]string, len(m))
+	for k, v := range m {
+		e.expand(&k)
+		e.expand(&v)
+		expanded[k] = v
+	}
+	return expanded
+}
@@ -0,0 +1,88 @@
+package render
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	v1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+)
+
+func newLatencyTemplate() *v1.RuleTemplate {
+	threshold := "0.5"
+	return &v1.RuleTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "latency", Namespace: "team-a"},
+		Spec: v1.RuleTemplateSpec{
+			Parameters: []v1.TemplateParameter{
+				{Name: "service"},
+				{Name: "threshold", Default: &threshold},
+			},
+			Groups: []v1.RuleGroup{
+				{
+					Name: "[[ .service ]].latency",
+					Rules: []v1.Rule{
+						{
+							Alert: "HighLatency",
+							Expr:  intstr.FromString(`histogram_quantile(0.99, rate(http_duration_seconds_bucket{service="[[ .service ]]"}[5m])) > [[ .threshold ]]`),
+							Labels: map[string]string{
+								"service": "[[ .service ]]",
+							},
+							Annotations: map[string]string{
+								"summary": "{{ $labels.service }} is slow",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+var _ = Describe("Rule templates", func() {
+	It("Should instantiate templates with values and defaults", func() {
+		groups, err := Instantiate(newLatencyTemplate().Spec, map[string]string{"service": "api"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(groups).To(HaveLen(1))
+		Expect(groups[0].Name).To(Equal("api.latency"))
+		rule := groups[0].Rules[0]
+		Expect(rule.Expr.String()).To(Equal(`histogram_quantile(0.99, rate(http_duration_seconds_bucket{service="api"}[5m])) > 0.5`))
+		Expect(rule.Labels).To(HaveKeyWithValue("service", "api"))
+		Expect(rule.Annotations).To(HaveKeyWithValue("summary", "{{ $labels.service }} is slow"))
+	})
+
+	It("Should reject missing and unknown parameters", func() {
+		_, err := Instantiate(newLatencyTemplate().Spec, nil)
+		Expect(err).To(MatchError(`parameter "service" is required`))
+
+		_, err = Instantiate(newLatencyTemplate().Spec, map[string]string{"service": "api", "team": "a"})
+		Expect(err).To(MatchError(`unknown parameter "team"`))
+	})
+
+	It("Should add the instantiated groups to a copy of the rule", func() {
+		rule := newRule()
+		rule.Spec.Templates = []v1.TemplateInstance{
+			{Name: "latency", Values: map[string]string{"service": "api"}},
+			{Name: "latency", Values: map[string]string{"service": "web", "threshold": "1"}},
+		}
+		lookup := func(name string) (*v1.RuleTemplate, error) {
+			if name != "latency" {
+				return nil, errors.New("not found")
+			}
+			return newLatencyTemplate(), nil
+		}
+
+		expanded, err := ExpandTemplates(&rule, lookup)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(expanded.RuleSpec().Groups).To(HaveLen(3))
+		Expect(expanded.RuleSpec().Groups[2].Name).To(Equal("web.latency"))
+		Expect(expanded.RuleSpec().Templates).To(BeEmpty())
+		Expect(rule.Spec.Groups).To(HaveLen(1))
+
+		rule.Spec.Templates = append(rule.Spec.Templates, rule.Spec.Templates[0])
+		_, err = ExpandTemplates(&rule, lookup)
+		Expect(err).To(MatchError(`rule group "api.latency" of template latency is already defined`))
+	})
+})
@@ -0,0 +1,85 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-logr/logr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	monitoringv1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+	"github.com/bolindalabs/cortex-alert-operator/controllers/render"
+)
+
+// defaultRulePath is the path the RuleDefaulter is served at.
+const defaultRulePath = "/mutate-monitoring-bolinda-digital-v1-rules"
+
+//+kubebuilder:webhook:path=/mutate-monitoring-bolinda-digital-v1-rules,mutating=true,failurePolicy=fail,sideEffects=None,groups=monitoring.bolinda.digital,resources=prometheusrules;clusterprometheusrules,verbs=create;update,versions=v1,name=mrules.monitoring.bolinda.digital,admissionReviewVersions={v1,v1beta1}
+
+// RuleDefaulter is a mutating admission webhook setting the operator defaults on the
+// rule groups of PrometheusRules and ClusterPrometheusRules, so they are stored as synced.
+type RuleDefaulter struct {
+	Log      logr.Logger
+	Defaults render.Defaults
+
+	decoder *admission.Decoder
+}
+
+// Handle sets the defaults on a created or updated PrometheusRule or ClusterPrometheusRule.
+func (d *RuleDefaulter) Handle(ctx context.Context, req admission.Request) admission.Response {
+	var rule monitoringv1.RuleObject
+	switch req.Kind.Kind {
+	case "PrometheusRule":
+		rule = &monitoringv1.PrometheusRule{}
+	case "ClusterPrometheusRule":
+		rule = &monitoringv1.ClusterPrometheusRule{}
+	default:
+		return admission.Allowed("")
+	}
+	if err := d.decoder.Decode(req, rule); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	if !d.Defaults.Apply(rule.RuleSpec()) {
+		return admission.Allowed("")
+	}
+
+	marshaled, err := json.Marshal(rule)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	d.Log.V(1).Info("applying defaults", "kind", req.Kind.Kind, "name", req.Name, "namespace", req.Namespace)
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaled)
+}
+
+// SetupWebhookWithManager registers the webhook with the webhook server of the Manager.
+func (d *RuleDefaulter) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	decoder, err := admission.NewDecoder(mgr.GetScheme())
+	if err != nil {
+		return err
+	}
+	d.decoder = decoder
+
+	mgr.GetWebhookServer().Register(defaultRulePath, &webhook.Admission{Handler: d})
+	return nil
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/equality"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	monitoringv1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+	"github.com/bolindalabs/cortex-alert-operator/controllers/cortex"
+	"github.com/bolindalabs/cortex-alert-operator/controllers/render"
+)
+
+// validateRulePath is the path the RuleValidator is served at.
+const validateRulePath = "/validate-monitoring-bolinda-digital-v1-rules"
+
+//+kubebuilder:webhook:path=/validate-monitoring-bolinda-digital-v1-rules,mutating=false,failurePolicy=fail,sideEffects=None,groups=monitoring.bolinda.digital,resources=prometheusrules;clusterprometheusrules,verbs=create;update,versions=v1,name=vrules.monitoring.bolinda.digital,admissionReviewVersions={v1,v1beta1}
+
+// RuleValidator is a validating admission webhook rejecting PrometheusRules and
+// ClusterPrometheusRules that name a Cortex namespace they may not use, or whose rendered
+// rule groups violate a RulePolicy or exceed the quotas.
+type RuleValidator struct {
+	Client   client.Client
+	Log      logr.Logger
+	Renderer render.Renderer
+	Quota    QuotaLimits
+
+	// Cortex and Loki are the rulers whose limits are added to the tenant quota, like RulerLimits.
+	// Loki is optional.
+	Cortex *cortex.Client
+	Loki   *cortex.Client
+	// RulerLimits are the configured ruler limits. Unset limits are read from the ruler, if served.
+	RulerLimits cortex.RulerLimits
+	// SplitGroups splits rule groups with more rules than the ruler allows before the quotas are checked,
+	// as the reconciler does.
+	SplitGroups bool
+
+	decoder *admission.Decoder
+}
+
+// Handle validates a created or updated PrometheusRule or ClusterPrometheusRule.
+func (v *RuleValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	rule := newRuleObject(req.Kind.Kind)
+	if rule == nil {
+		return admission.Allowed("")
+	}
+	if err := v.decoder.Decode(req, rule); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	// Adding and removing the finalizer must not be blocked by policies or quotas
+	// that changed since the rules were admitted.
+	if rule.GetDeletionTimestamp() != nil {
+		return admission.Allowed("")
+	}
+	if len(req.OldObject.Raw) > 0 {
+		old := newRuleObject(req.Kind.Kind)
+		if err := v.decoder.DecodeRaw(req.OldObject, old); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if sameRules(old, rule) {
+			return admission.Allowed("")
+		}
+	}
+
+	log := v.Log.WithValues("kind", req.Kind.Kind, "name", req.Name, "namespace", req.Namespace)
+
+	cortexNamespace, err := v.Renderer.CortexNamespace(rule)
+	if err != nil {
+		return admission.Denied(err.Error())
+	}
+
+	// Rules that cannot be rendered yet, e.g. because a template is created after them,
+	// are admitted and reported by the reconciler.
+	expanded, err := render.ExpandTemplates(rule, func(name string) (*monitoringv1.RuleTemplate, error) {
+		var tmpl monitoringv1.RuleTemplate
+		if err := v.Client.Get(ctx, client.ObjectKey{Namespace: rule.GetNamespace(), Name: name}, &tmpl); err != nil {
+			return nil, err
+		}
+		return &tmpl, nil
+	})
+	if err != nil {
+		return admission.Allowed("").WithWarnings(fmt.Sprintf("rule templates not checked: %v", err))
+	}
+	result, err := v.Renderer.Render(expanded, cortexNamespace)
+	if err != nil {
+		return admission.Allowed("").WithWarnings(fmt.Sprintf("rule groups cannot be rendered: %v", err))
+	}
+
+	policies, err := applicablePolicies(ctx, v.Client, rule)
+	if err != nil {
+		log.Error(err, "unable to get rule policies")
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	violations, err := checkPolicies(rule, result.Groups, policies)
+	if err != nil {
+		log.Error(err, "unable to check rule policies")
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if len(violations) > 0 {
+		log.Info("rejecting rules violating rule policies", "violations", len(violations))
+		return admission.Denied(fmt.Sprintf("rules violate rule policies:\n%s", formatViolations(violations)))
+	}
+
+	limits := v.rulerLimits(log, rule)
+	groups := result.Groups
+	if v.SplitGroups {
+		if groups, err = render.SplitGroups(groups, limits.MaxRulesPerRuleGroup); err != nil {
+			return admission.Allowed("").WithWarnings(fmt.Sprintf("rule groups cannot be split: %v", err))
+		}
+	}
+
+	exceeded, err := checkQuota(ctx, v.Client, rule, groups, policies, quotaLimits(v.Quota, limits))
+	if err != nil {
+		log.Error(err, "unable to check rule quotas")
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if len(exceeded) > 0 {
+		log.Info("rejecting rules exceeding quotas", "exceeded", exceeded)
+		return admission.Denied(fmt.Sprintf("rules exceed quotas: %s", strings.Join(exceeded, "; ")))
+	}
+	return admission.Allowed("")
+}
+
+// rulerLimits returns the limits of the ruler rule is synced to, like the reconciler does.
+func (v *RuleValidator) rulerLimits(log logr.Logger, rule monitoringv1.RuleObject) cortex.RulerLimits {
+	ruler := v.Cortex
+	if rule.RuleSpec().Backend == monitoringv1.BackendLoki {
+		ruler = v.Loki
+	}
+	if ruler == nil || (!v.SplitGroups && v.Quota == (QuotaLimits{})) {
+		return v.RulerLimits
+	}
+	return completeRulerLimits(log, ruler, v.RulerLimits)
+}
+
+// newRuleObject returns an empty PrometheusRule or ClusterPrometheusRule for kind, or nil for other kinds.
+func newRuleObject(kind string) monitoringv1.RuleObject {
+	switch kind {
+	case "PrometheusRule":
+		return &monitoringv1.PrometheusRule{}
+	case "ClusterPrometheusRule":
+		return &monitoringv1.ClusterPrometheusRule{}
+	}
+	return nil
+}
+
+// sameRules checks if old and rule render the same rule groups, i.e. only their status or
+// metadata other than annotations differ.
+func sameRules(old, rule monitoringv1.RuleObject) bool {
+	return equality.Semantic.DeepEqual(old.RuleSpec(), rule.RuleSpec()) &&
+		equality.Semantic.DeepEqual(old.GetAnnotations(), rule.GetAnnotations())
+}
+
+// SetupWebhookWithManager registers the webhook with the webhook server of the Manager.
+func (v *RuleValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	decoder, err := admission.NewDecoder(mgr.GetScheme())
+	if err != nil {
+		return err
+	}
+	v.decoder = decoder
+
+	mgr.GetWebhookServer().Register(validateRulePath, &webhook.Admission{Handler: v})
+	return nil
+}
@@ -0,0 +1,538 @@
+package ruletest
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/rules"
+	"github.com/prometheus/prometheus/storage"
+
+	v1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+)
+
+// defaultInterval is used for input series and rule groups without an interval, as in promtool.
+const defaultInterval = model.Duration(time.Minute)
+
+// Limits bound the resources tests may use, so they cannot stall or exhaust the operator.
+// Zero values are replaced by the DefaultLimits.
+type Limits struct {
+	// Timeout is the maximum duration of all tests of a Run together.
+	Timeout time.Duration
+	// MaxSeries is the maximum number of input series of a test.
+	MaxSeries int
+	// MaxSamples is the maximum number of input samples of a test, after expanding notations like 0x10.
+	MaxSamples int
+	// MaxEvalTime is the latest eval_time a test may check.
+	MaxEvalTime time.Duration
+}
+
+// DefaultLimits are applied to tests if no other limits are set.
+var DefaultLimits = Limits{
+	Timeout:     10 * time.Second,
+	MaxSeries:   1000,
+	MaxSamples:  100000,
+	MaxEvalTime: 24 * time.Hour,
+}
+
+// withDefaults returns l with the DefaultLimits in place of zero values.
+func (l Limits) withDefaults() Limits {
+	if l.Timeout <= 0 {
+		l.Timeout = DefaultLimits.Timeout
+	}
+	if l.MaxSeries <= 0 {
+		l.MaxSeries = DefaultLimits.MaxSeries
+	}
+	if l.MaxSamples <= 0 {
+		l.MaxSamples = DefaultLimits.MaxSamples
+	}
+	if l.MaxEvalTime <= 0 {
+		l.MaxEvalTime = DefaultLimits.MaxEvalTime
+	}
+	return l
+}
+
+// Run evaluates tests against groups with the Prometheus rule engine and returns
+// a description of every failed expectation. Tests behave like promtool test rules.
+// Tests exceeding limits fail, tests still running when the timeout expires are aborted.
+func Run(groups []v1.RuleGroup, tests []v1.RuleTest, limits Limits) []string {
+	limits = limits.withDefaults()
+	ctx, cancel := context.WithTimeout(context.Background(), limits.Timeout)
+	defer cancel()
+
+	var failures []string
+	for i, test := range tests {
+		name := test.Name
+		if name == "" {
+			name = strconv.Itoa(i)
+		}
+
+		for _, err := range run(ctx, groups, test, limits) {
+			failures = append(failures, fmt.Sprintf("test %s: %v", name, err))
+		}
+	}
+	return failures
+}
+
+// run evaluates a single test.
+func run(ctx context.Context, groups []v1.RuleGroup, test v1.RuleTest, limits Limits) (errs []error) {
+	if err := ctx.Err(); err != nil {
+		return []error{fmt.Errorf("not run, tests exceeded the timeout of %s", limits.Timeout)}
+	}
+
+	tc, err := parseTest(test)
+	if err != nil {
+		return []error{err}
+	}
+	if err := tc.checkLimits(limits); err != nil {
+		return []error{err}
+	}
+
+	// The test storage reports failures through Fatal, which run turns into an error.
+	defer func() {
+		if r := recover(); r != nil {
+			f, ok := r.(fatal)
+			if !ok {
+				panic(r)
+			}
+			errs = append(errs, f)
+		}
+	}()
+
+	suite, err := promql.NewLazyLoader(fatalT{}, tc.loadCommand())
+	if err != nil {
+		return []error{err}
+	}
+	defer suite.Close()
+
+	opts := &rules.ManagerOptions{
+		QueryFunc:  rules.EngineQueryFunc(suite.QueryEngine(), suite.Storage()),
+		Appendable: suite.Storage(),
+		Context:    ctx,
+		NotifyFunc: func(ctx context.Context, expr string, alerts ...*rules.Alert) {},
+		Logger:     log.NewNopLogger(),
+	}
+
+	ruleGroups, evalInterval, err := newGroups(groups, tc.externalLabels, opts)
+	if err != nil {
+		return []error{err}
+	}
+
+	mint := time.Unix(0, 0).UTC()
+	maxt := mint.Add(tc.maxEvalTime())
+
+	alertTests := make(map[time.Duration][]alertTestCase)
+	var alertEvalTimes []time.Duration
+	for _, a := range tc.alertTests {
+		if _, ok := alertTests[a.evalTime]; !ok {
+			alertEvalTimes = append(alertEvalTimes, a.evalTime)
+		}
+		alertTests[a.evalTime] = append(alertTests[a.evalTime], a)
+	}
+	sort.Slice(alertEvalTimes, func(i, j int) bool { return alertEvalTimes[i] < alertEvalTimes[j] })
+
+	// Mark alerting rules as restored, so the ALERTS series is created when they are evaluated.
+	for _, g := range ruleGroups {
+		for _, r := range g.Rules() {
+			if ar, ok := r.(*rules.AlertingRule); ok {
+				ar.SetRestored(true)
+			}
+		}
+	}
+
+	curr := 0
+	for ts := mint; !ts.After(maxt); ts = ts.Add(evalInterval) {
+		if ctx.Err() != nil {
+			return append(errs, aborted(ts.Sub(mint), limits))
+		}
+
+		suite.WithSamplesTill(ts, func(err error) {
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			for _, g := range ruleGroups {
+				g.Eval(ctx, ts)
+				for _, r := range g.Rules() {
+					if r.LastError() != nil {
+						errs = append(errs, fmt.Errorf("rule: %s, time: %s, err: %v", r.Name(), ts.Sub(mint), r.LastError()))
+					}
+				}
+			}
+		})
+		// Queries cut off by the timeout fail the rules, which is not their fault.
+		if ctx.Err() != nil {
+			return []error{aborted(ts.Sub(mint), limits)}
+		}
+		if len(errs) > 0 {
+			return errs
+		}
+
+		// Alerts expected at eval_time are compared to the evaluation at ts,
+		// if ts <= eval_time < ts+evalInterval.
+		for curr < len(alertEvalTimes) && ts.Sub(mint) <= alertEvalTimes[curr] && alertEvalTimes[curr] < ts.Add(evalInterval).Sub(mint) {
+			for _, a := range alertTests[alertEvalTimes[curr]] {
+				if err := a.check(ruleGroups); err != nil {
+					errs = append(errs, err)
+				}
+			}
+			curr++
+		}
+	}
+
+	for _, p := range tc.promqlTests {
+		err := p.check(ctx, mint, suite.QueryEngine(), suite.Queryable())
+		if ctx.Err() != nil {
+			return append(errs, aborted(p.evalTime, limits))
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// aborted is the error of a test aborted at time ts because the tests exceeded the timeout.
+func aborted(ts time.Duration, limits Limits) error {
+	return fmt.Errorf("aborted at time %s, tests exceeded the timeout of %s", ts, limits.Timeout)
+}
+
+// newGroups creates the Prometheus rule groups for groups. It returns them with the
+// shortest group interval, which is used as evaluation interval of the test.
+func newGroups(groups []v1.RuleGroup, externalLabels labels.Labels, opts *rules.ManagerOptions) ([]*rules.Group, time.Duration, error) {
+	evalInterval := time.Duration(defaultInterval)
+	ruleGroups := make([]*rules.Group, 0, len(groups))
+	for i, g := range groups {
+		interval := time.Duration(defaultInterval)
+		if g.Interval != "" {
+			d, err := model.ParseDuration(g.Interval)
+			if err != nil {
+				return nil, 0, fmt.Errorf("group %s: invalid interval: %w", g.Name, err)
+			}
+			interval = time.Duration(d)
+		}
+		if i == 0 || interval < evalInterval {
+			evalInterval = interval
+		}
+
+		rs := make([]rules.Rule, 0, len(g.Rules))
+		for _, r := range g.Rules {
+			rule, err := newRule(r, externalLabels)
+			if err != nil {
+				return nil, 0, fmt.Errorf("group %s: %w", g.Name, err)
+			}
+			rs = append(rs, rule)
+		}
+
+		ruleGroups = append(ruleGroups, rules.NewGroup(rules.GroupOptions{
+			Name:     g.Name,
+			Interval: interval,
+			Rules:    rs,
+			Opts:     opts,
+		}))
+	}
+	return ruleGroups, evalInterval, nil
+}
+
+// newRule creates the Prometheus alerting or recording rule for r.
+func newRule(r v1.Rule, externalLabels labels.Labels) (rules.Rule, error) {
+	expr, err := parser.ParseExpr(r.Expr.String())
+	if err != nil {
+		return nil, fmt.Errorf("rule %s%s: %w", r.Alert, r.Record, err)
+	}
+
+	if r.Record != "" {
+		return rules.NewRecordingRule(r.Record, expr, labels.FromMap(r.Labels)), nil
+	}
+
+	var hold model.Duration
+	if r.For != "" {
+		if hold, err = model.ParseDuration(r.For); err != nil {
+			return nil, fmt.Errorf("rule %s: invalid for: %w", r.Alert, err)
+		}
+	}
+
+	return rules.NewAlertingRule(r.Alert, expr, time.Duration(hold),
+		labels.FromMap(r.Labels), labels.FromMap(r.Annotations), externalLabels, true, log.NewNopLogger()), nil
+}
+
+// fatal is raised by fatalT and recovered by run.
+type fatal string
+
+func (f fatal) Error() string {
+	return string(f)
+}
+
+// fatalT lets the Prometheus test storage abort a test without a testing.T.
+type fatalT struct{}
+
+func (fatalT) Fatal(args ...interface{}) {
+	panic(fatal(fmt.Sprint(args...)))
+}
+
+func (fatalT) Fatalf(format string, args ...interface{}) {
+	panic(fatal(fmt.Sprintf(format, args...)))
+}
+
+// test is a parsed v1.RuleTest.
+type test struct {
+	interval       model.Duration
+	inputSeries    []v1.InputSeries
+	externalLabels labels.Labels
+	alertTests     []alertTestCase
+	promqlTests    []promqlTestCase
+}
+
+func parseTest(t v1.RuleTest) (*test, error) {
+	tc := &test{
+		interval:       defaultInterval,
+		inputSeries:    t.InputSeries,
+		externalLabels: labels.FromMap(t.ExternalLabels),
+	}
+
+	if t.Interval != "" {
+		d, err := model.ParseDuration(t.Interval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval: %w", err)
+		}
+		tc.interval = d
+	}
+
+	for _, a := range t.AlertRuleTests {
+		evalTime, err := model.ParseDuration(a.EvalTime)
+		if err != nil {
+			return nil, fmt.Errorf("alertname %s: invalid eval_time: %w", a.Alertname, err)
+		}
+		tc.alertTests = append(tc.alertTests, alertTestCase{
+			evalTime:  time.Duration(evalTime),
+			alertname: a.Alertname,
+			expAlerts: a.ExpAlerts,
+		})
+	}
+
+	for _, p := range t.PromQLTests {
+		evalTime, err := model.ParseDuration(p.EvalTime)
+		if err != nil {
+			return nil, fmt.Errorf("expr %q: invalid eval_time: %w", p.Expr, err)
+		}
+
+		pc := promqlTestCase{expr: p.Expr, evalTime: time.Duration(evalTime)}
+		for _, s := range p.ExpSamples {
+			lset, err := parser.ParseMetric(s.Labels)
+			if err != nil {
+				return nil, fmt.Errorf("expr %q: labels %q: %w", p.Expr, s.Labels, err)
+			}
+			value, err := strconv.ParseFloat(s.Value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("expr %q: value %q: %w", p.Expr, s.Value, err)
+			}
+			pc.expSamples = append(pc.expSamples, sample{labels: lset, value: value})
+		}
+		tc.promqlTests = append(tc.promqlTests, pc)
+	}
+	return tc, nil
+}
+
+// checkLimits checks that t stays within limits before any input series is loaded.
+func (t *test) checkLimits(limits Limits) error {
+	if len(t.inputSeries) > limits.MaxSeries {
+		return fmt.Errorf("%d input series exceed the limit of %d", len(t.inputSeries), limits.MaxSeries)
+	}
+
+	samples := 0
+	for _, s := range t.inputSeries {
+		samples += countSamples(s.Values)
+		if samples > limits.MaxSamples {
+			return fmt.Errorf("input series exceed the limit of %d samples", limits.MaxSamples)
+		}
+	}
+
+	if max := t.maxEvalTime(); max > limits.MaxEvalTime {
+		return fmt.Errorf("eval_time %s exceeds the limit of %s", model.Duration(max), model.Duration(limits.MaxEvalTime))
+	}
+	return nil
+}
+
+// countSamples returns an upper bound of the samples described by values in expanding notation,
+// without expanding them. Values with an invalid repetition count are counted as too many.
+func countSamples(values string) int {
+	n := 0
+	for _, v := range strings.Fields(values) {
+		i := strings.LastIndex(v, "x")
+		if i < 0 {
+			n++
+			continue
+		}
+
+		times, err := strconv.Atoi(v[i+1:])
+		if err != nil || times < 0 || times >= math.MaxInt32-n {
+			return math.MaxInt32
+		}
+		n += times + 1
+	}
+	return n
+}
+
+// loadCommand returns the input series in the notation of the PromQL test language.
+func (t *test) loadCommand() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "load %s\n", t.interval)
+	for _, s := range t.inputSeries {
+		fmt.Fprintf(&b, "  %s %s\n", s.Series, s.Values)
+	}
+	return b.String()
+}
+
+// maxEvalTime returns the latest eval_time of all test cases.
+func (t *test) maxEvalTime() time.Duration {
+	var max time.Duration
+	for _, a := range t.alertTests {
+		if a.evalTime > max {
+			max = a.evalTime
+		}
+	}
+	for _, p := range t.promqlTests {
+		if p.evalTime > max {
+			max = p.evalTime
+		}
+	}
+	return max
+}
+
+type alertTestCase struct {
+	evalTime  time.Duration
+	alertname string
+	expAlerts []v1.ExpectedAlert
+}
+
+// check compares the firing alerts of the alerting rules named alertname in groups with the expected alerts.
+func (a alertTestCase) check(groups []*rules.Group) error {
+	var got alerts
+	for _, g := range groups {
+		for _, r := range g.Rules() {
+			ar, ok := r.(*rules.AlertingRule)
+			if !ok || ar.Name() != a.alertname {
+				continue
+			}
+			for _, active := range ar.ActiveAlerts() {
+				if active.State == rules.StateFiring {
+					got = append(got, alert{
+						labels:      append(labels.Labels{}, active.Labels...),
+						annotations: append(labels.Labels{}, active.Annotations...),
+					})
+				}
+			}
+		}
+	}
+
+	var exp alerts
+	for _, e := range a.expAlerts {
+		// Users only list the labels of the alerting rule, the alertname is added on evaluation.
+		lbls := map[string]string{labels.AlertName: a.alertname}
+		for k, v := range e.ExpLabels {
+			lbls[k] = v
+		}
+		exp = append(exp, alert{
+			labels:      labels.FromMap(lbls),
+			annotations: labels.FromMap(e.ExpAnnotations),
+		})
+	}
+
+	sort.Sort(got)
+	sort.Sort(exp)
+	if len(got) != len(exp) || !reflect.DeepEqual(got, exp) {
+		return fmt.Errorf("alertname: %s, time: %s, exp: %s, got: %s", a.alertname, model.Duration(a.evalTime), exp, got)
+	}
+	return nil
+}
+
+type alert struct {
+	labels      labels.Labels
+	annotations labels.Labels
+}
+
+type alerts []alert
+
+func (a alerts) Len() int      { return len(a) }
+func (a alerts) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a alerts) Less(i, j int) bool {
+	if diff := labels.Compare(a[i].labels, a[j].labels); diff != 0 {
+		return diff < 0
+	}
+	return labels.Compare(a[i].annotations, a[j].annotations) < 0
+}
+
+func (a alerts) String() string {
+	s := make([]string, 0, len(a))
+	for _, al := range a {
+		s = append(s, "labels: "+al.labels.String()+" annotations: "+al.annotations.String())
+	}
+	return "[" + strings.Join(s, ", ") + "]"
+}
+
+type promqlTestCase struct {
+	expr       string
+	evalTime   time.Duration
+	expSamples samples
+}
+
+// check compares the result of the expression at evalTime with the expected samples.
+func (p promqlTestCase) check(ctx context.Context, mint time.Time, engine *promql.Engine, queryable storage.Queryable) error {
+	q, err := engine.NewInstantQuery(queryable, p.expr, mint.Add(p.evalTime))
+	if err != nil {
+		return fmt.Errorf("expr: %q, time: %s, err: %v", p.expr, model.Duration(p.evalTime), err)
+	}
+	res := q.Exec(ctx)
+	if res.Err != nil {
+		return fmt.Errorf("expr: %q, time: %s, err: %v", p.expr, model.Duration(p.evalTime), res.Err)
+	}
+
+	var got samples
+	switch v := res.Value.(type) {
+	case promql.Vector:
+		for _, s := range v {
+			got = append(got, sample{labels: s.Metric.Copy(), value: s.V})
+		}
+	case promql.Scalar:
+		got = samples{{labels: labels.Labels{}, value: v.V}}
+	default:
+		return fmt.Errorf("expr: %q, time: %s, err: result is not a vector or scalar", p.expr, model.Duration(p.evalTime))
+	}
+
+	exp := append(samples{}, p.expSamples...)
+	sort.Sort(got)
+	sort.Sort(exp)
+	if len(got) != len(exp) || (len(got) > 0 && !reflect.DeepEqual(got, exp)) {
+		return fmt.Errorf("expr: %q, time: %s, exp: %s, got: %s", p.expr, model.Duration(p.evalTime), exp, got)
+	}
+	return nil
+}
+
+type sample struct {
+	labels labels.Labels
+	value  float64
+}
+
+type samples []sample
+
+func (s samples) Len() int           { return len(s) }
+func (s samples) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s samples) Less(i, j int) bool { return labels.Compare(s[i].labels, s[j].labels) < 0 }
+
+func (s samples) String() string {
+	str := make([]string, 0, len(s))
+	for _, smpl := range s {
+		str = append(str, smpl.labels.String()+" "+strconv.FormatFloat(smpl.value, 'E', -1, 64))
+	}
+	return "[" + strings.Join(str, ", ") + "]"
+}
@@ -0,0 +1,116 @@
+package ruletest
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	v1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+)
+
+func exampleGroups() []v1.RuleGroup {
+	return []v1.RuleGroup{
+		{
+			Name: "example.rules",
+			Rules: []v1.Rule{
+				{
+					Alert:       "InstanceDown",
+					Expr:        intstr.FromString(`up == 0`),
+					For:         "5m",
+					Labels:      map[string]string{"severity": "page"},
+					Annotations: map[string]string{"summary": "{{ $labels.instance }} is down"},
+				},
+				{
+					Record: "job:up:sum",
+					Expr:   intstr.FromString("sum by (job) (up)"),
+				},
+			},
+		},
+	}
+}
+
+func newTest() v1.RuleTest {
+	return v1.RuleTest{
+		Name:     "instance down",
+		Interval: "1m",
+		InputSeries: []v1.InputSeries{
+			{Series: `up{job="api", instance="a"}`, Values: "1 0x10"},
+			{Series: `up{job="api", instance="b"}`, Values: "1x10"},
+		},
+		AlertRuleTests: []v1.AlertTestCase{
+			{EvalTime: "3m", Alertname: "InstanceDown"},
+			{
+				EvalTime:  "10m",
+				Alertname: "InstanceDown",
+				ExpAlerts: []v1.ExpectedAlert{
+					{
+						ExpLabels:      map[string]string{"severity": "page", "job": "api", "instance": "a"},
+						ExpAnnotations: map[string]string{"summary": "a is down"},
+					},
+				},
+			},
+		},
+		PromQLTests: []v1.PromQLTestCase{
+			{
+				Expr:       "job:up:sum",
+				EvalTime:   "10m",
+				ExpSamples: []v1.ExpectedSample{{Labels: `job:up:sum{job="api"}`, Value: "1"}},
+			},
+		},
+	}
+}
+
+var _ = Describe("Run", func() {
+	It("Should pass if all expectations are met", func() {
+		Expect(Run(exampleGroups(), []v1.RuleTest{newTest()}, Limits{})).To(BeEmpty())
+	})
+
+	It("Should report unexpected alerts", func() {
+		test := newTest()
+		test.AlertRuleTests[1].ExpAlerts = nil
+
+		failures := Run(exampleGroups(), []v1.RuleTest{test}, Limits{})
+		Expect(failures).To(HaveLen(1))
+		Expect(failures[0]).To(HavePrefix("test instance down: alertname: InstanceDown, time: 10m"))
+	})
+
+	It("Should report unexpected samples", func() {
+		test := newTest()
+		test.PromQLTests[0].ExpSamples[0].Value = "2"
+
+		failures := Run(exampleGroups(), []v1.RuleTest{test}, Limits{})
+		Expect(failures).To(HaveLen(1))
+		Expect(failures[0]).To(HavePrefix(`test instance down: expr: "job:up:sum", time: 10m`))
+	})
+
+	It("Should report invalid input series", func() {
+		test := newTest()
+		test.InputSeries[0].Values = "not a value"
+
+		Expect(Run(exampleGroups(), []v1.RuleTest{test}, Limits{})).To(HaveLen(1))
+	})
+
+	It("Should fail tests exceeding the limits", func() {
+		Expect(Run(exampleGroups(), []v1.RuleTest{newTest()}, Limits{MaxSeries: 1})).To(ConsistOf(ContainSubstring("2 input series exceed")))
+		Expect(Run(exampleGroups(), []v1.RuleTest{newTest()}, Limits{MaxSamples: 20})).To(ConsistOf(ContainSubstring("limit of 20 samples")))
+		Expect(Run(exampleGroups(), []v1.RuleTest{newTest()}, Limits{MaxEvalTime: 5 * time.Minute})).To(ConsistOf(ContainSubstring("eval_time 10m exceeds")))
+
+		test := newTest()
+		test.InputSeries[0].Values = "0x99999999999999999999"
+		Expect(Run(exampleGroups(), []v1.RuleTest{test}, Limits{})).To(ConsistOf(ContainSubstring("samples")))
+	})
+
+	It("Should abort tests exceeding the timeout", func() {
+		test := newTest()
+		test.AlertRuleTests = append(test.AlertRuleTests, v1.AlertTestCase{EvalTime: "24h", Alertname: "InstanceDown"})
+		groups := exampleGroups()
+		groups[0].Interval = "1ms"
+
+		failures := Run(groups, []v1.RuleTest{test, test}, Limits{Timeout: 50 * time.Millisecond})
+		Expect(failures).To(HaveLen(2))
+		Expect(failures[0]).To(ContainSubstring("exceeded the timeout"))
+		Expect(failures[1]).To(ContainSubstring("not run"))
+	})
+})
@@ -0,0 +1,13 @@
+package ruletest
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestRuleTest(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "RuleTest Suite")
+}
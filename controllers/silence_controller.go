@@ -0,0 +1,247 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	monitoringv1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+	"github.com/bolindalabs/cortex-alert-operator/controllers/cortex"
+)
+
+const silenceFinalizerName = "silence.monitoring.bolinda.digital"
+
+// SilenceReconciler reconciles a Silence object
+type SilenceReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+
+	Cortex *cortex.Client
+
+	// Paused stops all writes to Cortex for every Silence.
+	Paused bool
+	// ResyncInterval, if set, is how often silences are looked up in the Alertmanager,
+	// so they are recreated if they were expired or lost.
+	ResyncInterval time.Duration
+}
+
+//+kubebuilder:rbac:groups=monitoring.bolinda.digital,resources=silences,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=monitoring.bolinda.digital,resources=silences/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=monitoring.bolinda.digital,resources=silences/finalizers,verbs=update
+
+// Reconcile creates the silence in the Alertmanager of the tenant, keeps it in line with the Silence
+// and expires it when the Silence is deleted.
+func (r *SilenceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("silence", req.NamespacedName)
+
+	var silence monitoringv1.Silence
+	if err := r.Get(ctx, req.NamespacedName, &silence); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			return ctrl.Result{}, nil
+		}
+
+		log.Error(err, "unable to fetch Silence")
+		return ctrl.Result{}, err
+	}
+
+	hasFinalizer := containsString(silence.ObjectMeta.Finalizers, silenceFinalizerName)
+	isDeletionScheduled := !silence.ObjectMeta.DeletionTimestamp.IsZero()
+
+	switch {
+	case !hasFinalizer && !isDeletionScheduled:
+		if err := r.addFinalizer(ctx, silence, log); err != nil {
+			log.Error(err, "unable to add finalizer")
+			return ctrl.Result{}, err
+		}
+	case r.isPaused(silence):
+		log.Info("reconciliation paused, leaving Cortex untouched")
+		if err := r.patchStatus(ctx, silence, func(status *monitoringv1.SilenceStatus) {
+			meta.SetStatusCondition(&status.Conditions, pausedCondition(true))
+		}); err != nil {
+			log.Error(err, "unable to set status")
+			return ctrl.Result{}, err
+		}
+	case isDeletionScheduled:
+		if id := silence.Status.SilenceID; id != "" {
+			if err := r.Cortex.ExpireSilence(log, id); err != nil && !errors.Is(err, cortex.ErrResourceNotFound) {
+				log.Error(err, "unable to expire silence")
+				return ctrl.Result{}, err
+			}
+		}
+		if hasFinalizer {
+			if err := r.removeFinalizer(ctx, silence, log); err != nil {
+				log.Error(err, "unable to remove finalizer")
+				return ctrl.Result{}, err
+			}
+		}
+	default:
+		return r.sync(ctx, silence, log)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// sync creates or updates the silence in the Alertmanager, unless it already ended.
+func (r *SilenceReconciler) sync(ctx context.Context, silence monitoringv1.Silence, log logr.Logger) (ctrl.Result, error) {
+	now := time.Now()
+	if !silence.Spec.EndsAt.Time.After(now) {
+		// The Alertmanager expires the silence on its own.
+		if err := r.patchStatus(ctx, silence, func(status *monitoringv1.SilenceStatus) {
+			status.SyncStatus = "ended"
+			status.State = cortex.SilenceStateExpired
+			meta.SetStatusCondition(&status.Conditions, pausedCondition(false))
+		}); err != nil {
+			log.Error(err, "unable to set status")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	desired := cortex.NewSilence(silence.Spec)
+	id := silence.Status.SilenceID
+
+	var current *cortex.Silence
+	if id != "" {
+		var err error
+		current, err = r.Cortex.GetSilence(log, id)
+		if err != nil && !errors.Is(err, cortex.ErrResourceNotFound) {
+			log.Error(err, "unable to get silence")
+			return ctrl.Result{}, r.setSyncStatus(ctx, silence, fmt.Sprintf("unable to get silence: %v", err), err)
+		}
+	}
+
+	state := ""
+	written := false
+	recreate := current == nil || (current.Status != nil && current.Status.State == cortex.SilenceStateExpired)
+	switch {
+	case recreate || !current.Equal(desired, now):
+		if recreate && id != "" {
+			log.Info("silence disappeared, recreating it", "id", id)
+			r.Recorder.Eventf(&silence, corev1.EventTypeWarning, "Recreated", "silence %s was expired or deleted in the Alertmanager, recreating it", id)
+		}
+		if !recreate {
+			desired.ID = id
+		}
+
+		newID, err := r.Cortex.SetSilence(log, desired)
+		if err != nil {
+			log.Error(err, "unable to set silence")
+			return ctrl.Result{}, r.setSyncStatus(ctx, silence, fmt.Sprintf("unable to set silence: %v", err), err)
+		}
+		id = newID
+		written = true
+	case current.Status != nil:
+		state = current.Status.State
+	}
+
+	if state == "" {
+		state = cortex.SilenceStateActive
+		if desired.StartsAt.After(now) {
+			state = cortex.SilenceStatePending
+		}
+	}
+
+	if written && r.Cortex.DryRun() {
+		// Nothing was written, the status keeps describing the silence in the Alertmanager, if any.
+		log.Info("dry-run: silence would change", "id", id)
+		r.Recorder.Event(&silence, corev1.EventTypeNormal, "DryRun", "The silence was not written to the Alertmanager in dry-run mode")
+		id, state = silence.Status.SilenceID, silence.Status.State
+	}
+
+	if err := r.patchStatus(ctx, silence, func(status *monitoringv1.SilenceStatus) {
+		status.SyncStatus = syncedStatus(r.Cortex)
+		status.SilenceID = id
+		status.State = state
+		meta.SetStatusCondition(&status.Conditions, pausedCondition(false))
+	}); err != nil {
+		log.Error(err, "unable to set status")
+		return ctrl.Result{}, err
+	}
+
+	// Come back when the silence ends, to report it as expired.
+	requeueAfter := silence.Spec.EndsAt.Time.Sub(now) + time.Second
+	if r.ResyncInterval > 0 && r.ResyncInterval < requeueAfter {
+		requeueAfter = r.ResyncInterval
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// setSyncStatus records a failed sync in the status and returns err.
+func (r *SilenceReconciler) setSyncStatus(ctx context.Context, silence monitoringv1.Silence, syncStatus string, err error) error {
+	if statusErr := r.patchStatus(ctx, silence, func(status *monitoringv1.SilenceStatus) {
+		status.SyncStatus = syncStatus
+	}); statusErr != nil {
+		r.Log.Error(statusErr, "unable to set status")
+		return statusErr
+	}
+	return err
+}
+
+// isPaused checks if writes to Cortex are paused for the current Silence, either by annotation or operator-wide.
+func (r *SilenceReconciler) isPaused(silence monitoringv1.Silence) bool {
+	if r.Paused {
+		return true
+	}
+	paused, _ := strconv.ParseBool(silence.Annotations[monitoringv1.PausedAnnotation])
+	return paused
+}
+
+// patchStatus applies mutate to the status of the current Silence.
+func (r *SilenceReconciler) patchStatus(ctx context.Context, silence monitoringv1.Silence, mutate func(*monitoringv1.SilenceStatus)) error {
+	newSilence := silence.DeepCopy()
+	mutate(&newSilence.Status)
+	return r.Status().Patch(ctx, newSilence, client.MergeFrom(&silence))
+}
+
+// removeFinalizer removes our finalizer from the current Silence.
+func (r *SilenceReconciler) removeFinalizer(ctx context.Context, silence monitoringv1.Silence, log logr.Logger) error {
+	log.Info("Removing finalizer")
+
+	newSilence := silence.DeepCopy()
+	newSilence.ObjectMeta.Finalizers = removeString(silence.ObjectMeta.Finalizers, silenceFinalizerName)
+	return r.Patch(ctx, newSilence, client.MergeFrom(&silence))
+}
+
+// addFinalizer patches the current Silence, so that it contains our finalizer.
+func (r *SilenceReconciler) addFinalizer(ctx context.Context, silence monitoringv1.Silence, log logr.Logger) error {
+	log.Info("Adding finalizer")
+
+	newSilence := silence.DeepCopy()
+	newSilence.ObjectMeta.Finalizers = append(newSilence.ObjectMeta.Finalizers, silenceFinalizerName)
+	return r.Patch(ctx, newSilence, client.MergeFrom(&silence))
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *SilenceReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&monitoringv1.Silence{}).
+		Complete(r)
+}
@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	monitoringv1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+	"github.com/bolindalabs/cortex-alert-operator/controllers/cortex"
+)
+
+var _ = Describe("Silence Controller", func() {
+	const (
+		timeout  = time.Second * 10
+		interval = time.Millisecond * 250
+	)
+
+	Context("When creating a Silence", func() {
+		It("Should create the silence once and expire it with the Silence", func() {
+			// Times are stored with a precision of seconds.
+			now := time.Now().Truncate(time.Second)
+			silence := &monitoringv1.Silence{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "monitoring.bolinda.digital/v1",
+					Kind:       "Silence",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-silence",
+					Namespace: "default",
+				},
+				Spec: monitoringv1.SilenceSpec{
+					Matchers:  []monitoringv1.SilenceMatcher{{Name: "alertname", Value: "ExampleAlert"}},
+					StartsAt:  metav1.NewTime(now),
+					EndsAt:    metav1.NewTime(now.Add(24 * time.Hour)),
+					CreatedBy: "operator",
+					Comment:   "maintenance",
+				},
+			}
+
+			// Any further POST is unhandled and fails the spec.
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("POST", "/alertmanager/api/v2/silences"),
+				ghttp.VerifyJSONRepresenting(cortex.NewSilence(silence.Spec)),
+				ghttp.RespondWith(http.StatusOK, `{"silenceID":"4f5a"}`),
+			))
+			stored := cortex.NewSilence(silence.Spec)
+			stored.ID = "4f5a"
+			stored.Status = &cortex.SilenceStatus{State: cortex.SilenceStateActive}
+			server.RouteToHandler("GET", "/alertmanager/api/v2/silence/4f5a", ghttp.RespondWithJSONEncoded(http.StatusOK, stored))
+			server.RouteToHandler("DELETE", "/alertmanager/api/v2/silence/4f5a", ghttp.RespondWith(http.StatusOK, ""))
+
+			ctx := context.Background()
+			Expect(k8sClient.Create(ctx, silence)).Should(Succeed())
+
+			lookupKey := types.NamespacedName{Name: "test-silence", Namespace: "default"}
+			Eventually(func() string {
+				var created monitoringv1.Silence
+				if err := k8sClient.Get(ctx, lookupKey, &created); err != nil {
+					return ""
+				}
+				return created.Status.SilenceID
+			}, timeout, interval).Should(Equal("4f5a"))
+			Consistently(func() int {
+				return countRequests("POST", "/alertmanager/api/v2/silences")
+			}, time.Second*2, interval).Should(Equal(1))
+
+			By("By deleting the Silence")
+			Expect(k8sClient.Delete(ctx, silence)).Should(Succeed())
+			Eventually(func() bool {
+				var deleted monitoringv1.Silence
+				return apierrors.IsNotFound(k8sClient.Get(ctx, lookupKey, &deleted))
+			}, timeout, interval).Should(BeTrue(), "Silence should be deleted")
+			Expect(countRequests("DELETE", "/alertmanager/api/v2/silence/4f5a")).To(Equal(1))
+		})
+	})
+})
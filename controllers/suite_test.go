@@ -23,6 +23,7 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/ghttp"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -45,6 +46,10 @@ var k8sClient client.Client
 var testEnv *envtest.Environment
 var server *ghttp.Server
 var prometheusRuleReconciler *PrometheusRuleReconciler
+var clusterPrometheusRuleReconciler *ClusterPrometheusRuleReconciler
+var alertmanagerConfigReconciler *AlertmanagerConfigReconciler
+var silenceReconciler *SilenceReconciler
+var configMapReconciler *ConfigMapReconciler
 
 func TestAPIs(t *testing.T) {
 	RegisterFailHandler(Fail)
@@ -95,14 +100,56 @@ var _ = BeforeSuite(func(done Done) {
 	}
 
 	prometheusRuleReconciler = &PrometheusRuleReconciler{
-		Client: k8sManager.GetClient(),
-		Cortex: cortexClient,
-		Log:    ctrl.Log.WithName("controllers").WithName("PrometheusRule"),
+		Client:   k8sManager.GetClient(),
+		Cortex:   cortexClient,
+		Log:      ctrl.Log.WithName("controllers").WithName("PrometheusRule"),
+		Recorder: k8sManager.GetEventRecorderFor("cortex-alert-operator"),
 	}
 
 	err = prometheusRuleReconciler.SetupWithManager(k8sManager)
 	Expect(err).ToNot(HaveOccurred())
 
+	clusterPrometheusRuleReconciler = &ClusterPrometheusRuleReconciler{PrometheusRuleReconciler{
+		Client:   k8sManager.GetClient(),
+		Cortex:   cortexClient,
+		Log:      ctrl.Log.WithName("controllers").WithName("ClusterPrometheusRule"),
+		Recorder: k8sManager.GetEventRecorderFor("cortex-alert-operator"),
+	}}
+
+	err = clusterPrometheusRuleReconciler.SetupWithManager(k8sManager)
+	Expect(err).ToNot(HaveOccurred())
+
+	alertmanagerConfigReconciler = &AlertmanagerConfigReconciler{
+		Client:   k8sManager.GetClient(),
+		Cortex:   cortexClient,
+		Log:      ctrl.Log.WithName("controllers").WithName("AlertmanagerConfig"),
+		Recorder: k8sManager.GetEventRecorderFor("cortex-alert-operator"),
+	}
+
+	err = alertmanagerConfigReconciler.SetupWithManager(k8sManager)
+	Expect(err).ToNot(HaveOccurred())
+
+	silenceReconciler = &SilenceReconciler{
+		Client:   k8sManager.GetClient(),
+		Cortex:   cortexClient,
+		Log:      ctrl.Log.WithName("controllers").WithName("Silence"),
+		Recorder: k8sManager.GetEventRecorderFor("cortex-alert-operator"),
+	}
+
+	err = silenceReconciler.SetupWithManager(k8sManager)
+	Expect(err).ToNot(HaveOccurred())
+
+	configMapReconciler = &ConfigMapReconciler{
+		Client:   k8sManager.GetClient(),
+		Scheme:   k8sManager.GetScheme(),
+		Log:      ctrl.Log.WithName("controllers").WithName("ConfigMap"),
+		Recorder: k8sManager.GetEventRecorderFor("cortex-alert-operator"),
+		Selector: labels.SelectorFromSet(labels.Set{"role": "alert-rules"}),
+	}
+
+	err = configMapReconciler.SetupWithManager(k8sManager)
+	Expect(err).ToNot(HaveOccurred())
+
 	go func() {
 		err = k8sManager.Start(ctrl.SetupSignalHandler())
 		Expect(err).ToNot(HaveOccurred())
@@ -133,6 +180,9 @@ var _ = BeforeEach(func() {
 	Expect(err).ToNot(HaveOccurred())
 
 	prometheusRuleReconciler.Cortex = cortexClient
+	clusterPrometheusRuleReconciler.Cortex = cortexClient
+	alertmanagerConfigReconciler.Cortex = cortexClient
+	silenceReconciler.Cortex = cortexClient
 })
 
 var _ = AfterEach(func() {
@@ -0,0 +1,223 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ghodss/yaml"
+	"github.com/pmezard/go-difflib/difflib"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	monitoringv1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+	"github.com/bolindalabs/cortex-alert-operator/controllers/cortex"
+	"github.com/bolindalabs/cortex-alert-operator/controllers/render"
+)
+
+// runDiff prints a unified diff between the rule groups of the PrometheusRules
+// in the cluster and the rule groups stored in Cortex, or Loki for rules of the Loki backend.
+// Groups the operator would delete from the Cortex namespace of a rule are diffed against /dev/null.
+// Like diff(1), it exits with 1 if there are differences and 2 on errors.
+func runDiff(args []string) int {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	var cortexOpts cortexFlags
+	var lokiOpts lokiFlags
+	var renderOpts renderFlags
+	var kubeconfig string
+	var namespace string
+	cortexOpts.bind(fs)
+	lokiOpts.bind(fs)
+	renderOpts.bind(fs)
+	fs.StringVar(&kubeconfig, "kubeconfig", "", "Path to a kubeconfig. Defaults to $KUBECONFIG or the in-cluster config.")
+	fs.StringVar(&namespace, "namespace", "", "Only diff PrometheusRules in this namespace. ClusterPrometheusRules are only diffed when unset.")
+	_ = fs.Parse(args)
+
+	log := ctrl.Log.WithName("diff")
+
+	renderer, err := renderOpts.renderer(cortexOpts.user, lokiOpts.user)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to load federation policy: %v\n", err)
+		return 2
+	}
+
+	cortexClient, err := cortexOpts.client(true)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to create Cortex client: %v\n", err)
+		return 2
+	}
+
+	lokiClient, err := lokiOpts.client(true)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to create Loki client: %v\n", err)
+		return 2
+	}
+
+	k8sClient, err := newKubeClient(kubeconfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to create Kubernetes client: %v\n", err)
+		return 2
+	}
+
+	var list monitoringv1.PrometheusRuleList
+	if err := k8sClient.List(context.Background(), &list, client.InNamespace(namespace)); err != nil {
+		fmt.Fprintf(os.Stderr, "unable to list PrometheusRules: %v\n", err)
+		return 2
+	}
+	var rules []monitoringv1.RuleObject
+	for i := range list.Items {
+		rules = append(rules, &list.Items[i])
+	}
+
+	if namespace == "" {
+		var clusterList monitoringv1.ClusterPrometheusRuleList
+		if err := k8sClient.List(context.Background(), &clusterList); err != nil {
+			fmt.Fprintf(os.Stderr, "unable to list ClusterPrometheusRules: %v\n", err)
+			return 2
+		}
+		for i := range clusterList.Items {
+			rules = append(rules, &clusterList.Items[i])
+		}
+	}
+
+	changed := false
+	for _, rule := range rules {
+		ruler := cortexClient
+		if rule.RuleSpec().Backend == monitoringv1.BackendLoki {
+			if lokiClient == nil {
+				fmt.Fprintf(os.Stderr, "skipping %s: no Loki ruler configured\n", ruleRef(rule))
+				continue
+			}
+			ruler = lokiClient
+		}
+
+		expanded, err := render.ExpandTemplates(rule, clusterTemplates(k8sClient, rule.GetNamespace()))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "unable to expand templates of %s: %v\n", ruleRef(rule), err)
+			return 2
+		}
+
+		cortexNamespace, err := renderer.CortexNamespace(rule)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "unable to render %s: %v\n", ruleRef(rule), err)
+			return 2
+		}
+		result, err := renderer.Render(expanded, cortexNamespace)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "unable to render %s: %v\n", ruleRef(rule), err)
+			return 2
+		}
+		if result.Groups, err = renderOpts.split(log, ruler, result.Groups); err != nil {
+			fmt.Fprintf(os.Stderr, "unable to split rule groups of %s: %v\n", ruleRef(rule), err)
+			return 2
+		}
+
+		for _, g := range result.Groups {
+			diff, err := ruler.DiffRuleGroup(log, cortexNamespace, g)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "unable to diff %s/%s: %v\n", cortexNamespace, g.Name, err)
+				return 2
+			}
+			if diff != "" {
+				changed = true
+				fmt.Print(diff)
+			}
+		}
+
+		current, err := ruler.GetRuleNamespace(log, cortexNamespace)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "unable to get rule groups of %s: %v\n", cortexNamespace, err)
+			return 2
+		}
+		for _, g := range current {
+			if containsGroup(result.Groups, g.Name) {
+				continue
+			}
+
+			diff, err := pruneDiff(cortexNamespace, g)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "unable to diff %s/%s: %v\n", cortexNamespace, g.Name, err)
+				return 2
+			}
+			changed = true
+			fmt.Print(diff)
+		}
+	}
+
+	if changed {
+		return 1
+	}
+	return 0
+}
+
+// pruneDiff returns a unified diff deleting group, which is no longer rendered, from namespace.
+func pruneDiff(namespace string, group cortex.RuleGroup) (string, error) {
+	actual, err := yaml.Marshal(group)
+	if err != nil {
+		return "", err
+	}
+
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(actual)),
+		FromFile: "cortex/" + namespace + "/" + group.Name,
+		ToFile:   "/dev/null",
+		Context:  3,
+	})
+}
+
+func containsGroup(groups []monitoringv1.RuleGroup, name string) bool {
+	for _, g := range groups {
+		if g.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// clusterTemplates returns a lookup of the RuleTemplates in namespace of the cluster.
+func clusterTemplates(c client.Client, namespace string) render.TemplateLookup {
+	return func(name string) (*monitoringv1.RuleTemplate, error) {
+		var tmpl monitoringv1.RuleTemplate
+		if err := c.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: name}, &tmpl); err != nil {
+			return nil, err
+		}
+		return &tmpl, nil
+	}
+}
+
+// newKubeClient creates a client for the cluster in kubeconfig, falling back to
+// the default config resolution of the manager.
+func newKubeClient(kubeconfig string) (client.Client, error) {
+	var cfg *rest.Config
+	var err error
+	if kubeconfig != "" {
+		cfg, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
+	} else {
+		cfg, err = ctrl.GetConfig()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return client.New(cfg, client.Options{Scheme: scheme})
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/go-logr/logr"
+
+	monitoringv1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+	"github.com/bolindalabs/cortex-alert-operator/controllers/cortex"
+	"github.com/bolindalabs/cortex-alert-operator/controllers/render"
+)
+
+// cortexFlags configure how the manager and the subcommands reach Cortex.
+type cortexFlags struct {
+	url                string
+	user               string
+	token              string
+	prometheusPrefix   string
+	alertmanagerPrefix string
+}
+
+func (f *cortexFlags) bind(fs *flag.FlagSet) {
+	fs.StringVar(&f.url, "cortex-url", "", "Cortex API Endpoint.")
+	fs.StringVar(&f.user, "cortex-user", "", "Cortex API Username.")
+	fs.StringVar(&f.token, "cortex-token", "", "Cortex API Token.")
+	fs.StringVar(&f.prometheusPrefix, "cortex-prometheus-prefix", cortex.DefaultPrometheusHTTPPrefix,
+		"Path prefix of the Prometheus compatible API of Cortex, used to read rule health.")
+	fs.StringVar(&f.alertmanagerPrefix, "cortex-alertmanager-prefix", cortex.DefaultAlertmanagerHTTPPrefix,
+		"Path prefix of the Alertmanager API of Cortex, used to manage silences.")
+}
+
+func (f *cortexFlags) client(dryRun bool) (*cortex.Client, error) {
+	return cortex.New(cortex.Config{
+		Key:             f.token,
+		Address:         f.url,
+		ID:              f.user,
+		UseLegacyRoutes: false,
+		DryRun:          dryRun,
+
+		PrometheusHTTPPrefix:   f.prometheusPrefix,
+		AlertmanagerHTTPPrefix: f.alertmanagerPrefix,
+	})
+}
+
+// lokiFlags configure how the manager and the subcommands reach the Loki ruler.
+type lokiFlags struct {
+	url   string
+	user  string
+	token string
+}
+
+func (f *lokiFlags) bind(fs *flag.FlagSet) {
+	fs.StringVar(&f.url, "loki-url", "", "Loki API Endpoint. PrometheusRules with the Loki backend are only synced if set.")
+	fs.StringVar(&f.user, "loki-user", "", "Loki API Username.")
+	fs.StringVar(&f.token, "loki-token", "", "Loki API Token.")
+}
+
+// client returns nil if no Loki endpoint is configured.
+func (f *lokiFlags) client(dryRun bool) (*cortex.Client, error) {
+	if f.url == "" {
+		return nil, nil
+	}
+
+	return cortex.New(cortex.Config{
+		Key:          f.token,
+		Address:      f.url,
+		ID:           f.user,
+		DryRun:       dryRun,
+		RulerAPIPath: cortex.LokiRulerAPIPath,
+	})
+}
+
+// defaultsFlags configure the defaults set on rules, so the defaulting webhook and render set the same.
+type defaultsFlags struct {
+	render.Defaults
+}
+
+func (f *defaultsFlags) bind(fs *flag.FlagSet) {
+	fs.StringVar(&f.Interval, "default-group-interval", "",
+		"Evaluation interval set on rule groups without one. Empty leaves it unset.")
+	fs.StringVar(&f.For, "default-alert-for", "",
+		"The for duration set on alerting rules without one. Empty leaves it unset.")
+	fs.StringVar(&f.Severity, "default-severity", "",
+		"Severity label set on alerting rules without one. Empty leaves it unset.")
+	fs.BoolVar(&f.LowercaseLabelKeys, "lowercase-label-keys", false,
+		"Lowercase the label keys of all rules.")
+}
+
+// renderFlags configure how PrometheusRules are turned into Cortex rule groups,
+// so the manager and the subcommands produce the same result.
+type renderFlags struct {
+	injectLabels          labelsFlag
+	enforceNamespaceLabel string
+	federationPolicyFile  string
+	namespaceOverrides    overridesFlag
+	rulerLimits           cortex.RulerLimits
+	splitGroups           bool
+}
+
+func (f *renderFlags) bind(fs *flag.FlagSet) {
+	f.injectLabels = labelsFlag{}
+	fs.Var(f.injectLabels, "inject-label", "Label in the form name=value to set on every rule. "+
+		"Values may reference ${namespace}, ${name}, ${cortex_namespace} and ${tenant}. Can be repeated.")
+	fs.StringVar(&f.enforceNamespaceLabel, "enforce-namespace-label", "",
+		"If set, every vector selector in rule expressions is restricted to this label matching the PrometheusRule namespace.")
+	fs.StringVar(&f.federationPolicyFile, "federation-policy", "",
+		"Path to a YAML file listing the source tenants each namespace may query in federated rule groups.")
+	fs.Var(&f.namespaceOverrides, "allow-cortex-namespace", "Allow the rules of a namespace to write to the Cortex namespaces "+
+		"matching a shell pattern through the cortex-namespace annotation, as namespace=pattern, e.g. monitoring=legacy-*. "+
+		"Use _cluster for ClusterPrometheusRules. Can be repeated.")
+	fs.IntVar(&f.rulerLimits.MaxRulesPerRuleGroup, "ruler-max-rules-per-rule-group", 0,
+		"The ruler_max_rules_per_rule_group limit of the tenant. 0 reads it from the user limits API of Cortex.")
+	fs.IntVar(&f.rulerLimits.MaxRuleGroupsPerTenant, "ruler-max-rule-groups-per-tenant", 0,
+		"The ruler_max_rule_groups_per_tenant limit of the tenant. 0 reads it from the user limits API of Cortex.")
+	fs.BoolVar(&f.splitGroups, "split-oversized-groups", false,
+		"Split rule groups with more rules than the ruler allows into numbered groups, instead of failing the sync.")
+}
+
+func (f *renderFlags) renderer(tenant, lokiTenant string) (render.Renderer, error) {
+	r := render.Renderer{
+		Labels:                f.injectLabels,
+		Tenant:                tenant,
+		LokiTenant:            lokiTenant,
+		EnforceNamespaceLabel: f.enforceNamespaceLabel,
+
+		CortexNamespaceOverrides: f.namespaceOverrides,
+	}
+
+	if f.federationPolicyFile != "" {
+		policy, err := render.LoadFederationPolicy(f.federationPolicyFile)
+		if err != nil {
+			return r, err
+		}
+		r.Federation = policy
+	}
+	return r, nil
+}
+
+// split splits oversized rule groups like the manager does with the same flags.
+// If the rules per group limit is unset, it is read from ruler; without a ruler, groups are not split.
+func (f *renderFlags) split(log logr.Logger, ruler *cortex.Client, groups []monitoringv1.RuleGroup) ([]monitoringv1.RuleGroup, error) {
+	if !f.splitGroups {
+		return groups, nil
+	}
+
+	maxRules := f.rulerLimits.MaxRulesPerRuleGroup
+	if maxRules == 0 && ruler != nil {
+		limits, err := ruler.GetRulerLimits(log)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get ruler limits: %w", err)
+		}
+		maxRules = limits.MaxRulesPerRuleGroup
+	}
+	return render.SplitGroups(groups, maxRules)
+}
+
+// labelsFlag collects repeated name=value flags into a label set.
+type labelsFlag map[string]string
+
+func (l labelsFlag) String() string {
+	pairs := make([]string, 0, len(l))
+	for k, v := range l {
+		pairs = append(pairs, k+"="+v)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (l labelsFlag) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return fmt.Errorf("invalid label %q, expected name=value", value)
+	}
+	l[parts[0]] = parts[1]
+	return nil
+}
+
+// overridesFlag collects repeated namespace=pattern flags into Cortex namespace overrides.
+type overridesFlag []render.CortexNamespaceOverride
+
+func (o *overridesFlag) String() string {
+	pairs := make([]string, 0, len(*o))
+	for _, override := range *o {
+		pairs = append(pairs, override.Namespace+"="+override.Pattern)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (o *overridesFlag) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return fmt.Errorf("invalid Cortex namespace override %q, expected namespace=pattern", value)
+	}
+	if _, err := path.Match(parts[1], ""); err != nil {
+		return fmt.Errorf("invalid Cortex namespace pattern %q: %w", parts[1], err)
+	}
+	*o = append(*o, render.CortexNamespaceOverride{Namespace: parts[0], Pattern: parts[1]})
+	return nil
+}
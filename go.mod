@@ -4,10 +4,17 @@ go 1.15
 
 require (
 	github.com/ghodss/yaml v1.0.0
+	github.com/go-kit/kit v0.10.0
 	github.com/go-logr/logr v0.3.0
 	github.com/onsi/ginkgo v1.14.1
 	github.com/onsi/gomega v1.10.2
-	k8s.io/apimachinery v0.19.2
-	k8s.io/client-go v0.19.2
+	github.com/pmezard/go-difflib v1.0.0
+	github.com/prometheus/client_golang v1.8.0
+	github.com/prometheus/common v0.15.0
+	github.com/prometheus/prometheus v1.8.2-0.20201119142752-3ad25a6dc3d9
+	k8s.io/api v0.19.4
+	k8s.io/apiextensions-apiserver v0.19.2
+	k8s.io/apimachinery v0.19.4
+	k8s.io/client-go v0.19.4
 	sigs.k8s.io/controller-runtime v0.7.2
 )
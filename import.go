@@ -0,0 +1,180 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/ghodss/yaml"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	monitoringv1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+	"github.com/bolindalabs/cortex-alert-operator/controllers/cortex"
+	"github.com/bolindalabs/cortex-alert-operator/controllers/render"
+)
+
+// runImport generates PrometheusRule manifests for the rule groups stored in Cortex,
+// optionally creating them in the cluster so the operator adopts the Cortex namespaces.
+func runImport(args []string) int {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	var cortexOpts cortexFlags
+	var kubeconfig string
+	var namespace string
+	var outputDir string
+	var apply bool
+	cortexOpts.bind(fs)
+	fs.StringVar(&kubeconfig, "kubeconfig", "", "Path to a kubeconfig. Defaults to $KUBECONFIG or the in-cluster config.")
+	fs.StringVar(&namespace, "namespace", "default",
+		"Namespace for Cortex namespaces that do not follow the {namespace}--{name} naming scheme.")
+	fs.StringVar(&outputDir, "output-dir", "", "Directory to write the manifests to. Defaults to stdout.")
+	fs.BoolVar(&apply, "apply", false, "Create the PrometheusRules in the cluster. Existing PrometheusRules are not changed.")
+	_ = fs.Parse(args)
+
+	log := ctrl.Log.WithName("import")
+
+	cortexClient, err := cortexOpts.client(false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to create Cortex client: %v\n", err)
+		return 2
+	}
+
+	rules, err := cortexClient.ListRules(log)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to list Cortex rules: %v\n", err)
+		return 2
+	}
+
+	manifests, skipped := importedRules(rules, namespace)
+	for _, cortexNamespace := range skipped {
+		fmt.Fprintf(os.Stderr, "skipping %s: generated from a ConfigMap, import the ConfigMap instead\n", cortexNamespace)
+	}
+	failed := 0
+
+	if apply {
+		k8sClient, err := newKubeClient(kubeconfig)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "unable to create Kubernetes client: %v\n", err)
+			return 2
+		}
+
+		// Every rule that can be created is, so a single failure does not hold back the others.
+		for _, rule := range manifests {
+			err := k8sClient.Create(context.Background(), rule.DeepCopyObject().(client.Object))
+			switch {
+			case apierrors.IsAlreadyExists(err):
+				fmt.Fprintf(os.Stderr, "skipping %s: already exists\n", ruleRef(rule))
+			case err != nil:
+				fmt.Fprintf(os.Stderr, "unable to create %s: %v\n", ruleRef(rule), err)
+				failed++
+			}
+		}
+	}
+
+	for i, rule := range manifests {
+		out, err := yaml.Marshal(rule)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "unable to marshal %s: %v\n", ruleRef(rule), err)
+			return 2
+		}
+
+		if outputDir == "" {
+			if i > 0 {
+				fmt.Println("---")
+			}
+			fmt.Print(string(out))
+		} else if err := ioutil.WriteFile(filepath.Join(outputDir, render.CortexNamespace(rule)+".yaml"), out, 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "unable to write %s: %v\n", ruleRef(rule), err)
+			return 2
+		}
+	}
+
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "unable to create %d of %d rules\n", failed, len(manifests))
+		return 2
+	}
+	return 0
+}
+
+// importedRules builds a PrometheusRule for every Cortex namespace in rules, or a ClusterPrometheusRule
+// for those of cluster-scoped rules. Cortex namespaces that cannot be mapped back by the naming scheme are
+// placed in defaultNamespace and pinned with the Cortex namespace annotation. The Cortex namespaces of
+// ConfigMaps are skipped, as the operator only writes to them for the ConfigMap.
+func importedRules(rules map[string][]cortex.RuleGroup, defaultNamespace string) (manifests []monitoringv1.RuleObject, skipped []string) {
+	cortexNamespaces := make([]string, 0, len(rules))
+	for ns := range rules {
+		cortexNamespaces = append(cortexNamespaces, ns)
+	}
+	sort.Strings(cortexNamespaces)
+
+	used := map[string]bool{}
+	for _, cortexNamespace := range cortexNamespaces {
+		if render.IsConfigMapCortexNamespace(cortexNamespace) {
+			skipped = append(skipped, cortexNamespace)
+			continue
+		}
+
+		var rule monitoringv1.RuleObject
+		if name, ok := render.ParseClusterCortexNamespace(cortexNamespace); ok {
+			rule = &monitoringv1.ClusterPrometheusRule{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       "ClusterPrometheusRule",
+					APIVersion: monitoringv1.GroupVersion.String(),
+				},
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+			}
+		} else {
+			namespace, name, ok := render.ParseCortexNamespace(cortexNamespace)
+			if !ok {
+				namespace = defaultNamespace
+				name = render.SanitizeName(cortexNamespace)
+			}
+			rule = &monitoringv1.PrometheusRule{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       "PrometheusRule",
+					APIVersion: monitoringv1.GroupVersion.String(),
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespace,
+				},
+			}
+		}
+		for base, i := rule.GetName(), 2; used[ruleRef(rule)]; i++ {
+			rule.SetName(fmt.Sprintf("%s-%d", base, i))
+		}
+		used[ruleRef(rule)] = true
+
+		if render.CortexNamespace(rule) != cortexNamespace {
+			rule.SetAnnotations(map[string]string{monitoringv1.CortexNamespaceAnnotation: cortexNamespace})
+		}
+
+		for _, g := range rules[cortexNamespace] {
+			rule.RuleSpec().Groups = append(rule.RuleSpec().Groups, g.ToV1())
+		}
+		manifests = append(manifests, rule)
+	}
+	return manifests, skipped
+}
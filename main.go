@@ -18,12 +18,15 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"os"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -33,13 +36,21 @@ import (
 
 	monitoringv1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
 	"github.com/bolindalabs/cortex-alert-operator/controllers"
-	"github.com/bolindalabs/cortex-alert-operator/controllers/cortex"
+	"github.com/bolindalabs/cortex-alert-operator/controllers/ruletest"
 	//+kubebuilder:scaffold:imports
 )
 
 var (
 	scheme   = runtime.NewScheme()
 	setupLog = ctrl.Log.WithName("setup")
+
+	// commands are the subcommands of the operator binary, keyed by name.
+	// Without a subcommand the binary runs the manager.
+	commands = map[string]func(args []string) int{
+		"diff":   runDiff,
+		"render": runRender,
+		"import": runImport,
+	}
 )
 
 func init() {
@@ -50,20 +61,69 @@ func init() {
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			os.Exit(command(os.Args[2:]))
+		}
+	}
+
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
-	var cortexURL string
-	var cortexUser string
-	var cortexToken string
+	var cortexOpts cortexFlags
+	var lokiOpts lokiFlags
+	var renderOpts renderFlags
+	var paused bool
+	var deletionPolicy string
+	var dryRun bool
+	var healthCheckInterval time.Duration
+	var silenceResyncInterval time.Duration
+	var configMapSelector string
+	var enableWebhooks bool
+	var quota controllers.QuotaLimits
+	var defaultsOpts defaultsFlags
+	var testLimits ruletest.Limits
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
-	flag.StringVar(&cortexURL, "cortex-url", "", "Cortex API Endpoint.")
-	flag.StringVar(&cortexUser, "cortex-user", "", "Cortex API Username.")
-	flag.StringVar(&cortexToken, "cortex-token", "", "Cortex API Token.")
+	cortexOpts.bind(flag.CommandLine)
+	lokiOpts.bind(flag.CommandLine)
+	renderOpts.bind(flag.CommandLine)
+	defaultsOpts.bind(flag.CommandLine)
+	flag.BoolVar(&paused, "paused", false,
+		"Freeze all writes to Cortex. PrometheusRules keep their finalizer but are neither synced nor deleted.")
+	flag.StringVar(&deletionPolicy, "deletion-policy", string(monitoringv1.DeletionPolicyDelete),
+		"What happens to the rules in Cortex when a PrometheusRule without its own policy is deleted. One of Delete, Retain or Orphan.")
+	flag.BoolVar(&dryRun, "dry-run", false,
+		"Only log, count and record events for the changes that would be written to Cortex. Reads still hit Cortex.")
+	flag.DurationVar(&healthCheckInterval, "health-check-interval", 0,
+		"How often to resync PrometheusRules and refresh the rule health and alerts reported by the Cortex ruler. 0 disables health checks.")
+	flag.DurationVar(&silenceResyncInterval, "silence-resync-interval", 5*time.Minute,
+		"How often to check that the silences of Silence resources still exist in the Alertmanager. 0 only checks on changes.")
+	flag.StringVar(&configMapSelector, "configmap-selector", "",
+		"Label selector of ConfigMaps holding Prometheus rule files to sync, e.g. role=alert-rules. Empty disables ConfigMap rule sources.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", os.Getenv("ENABLE_WEBHOOKS") == "true",
+		"Serve the admission webhooks. Requires a serving certificate, see config/certmanager. Defaults to $ENABLE_WEBHOOKS.")
+	flag.IntVar(&quota.Namespace.MaxGroups, "max-rule-groups-per-namespace", 0,
+		"Maximum number of rule groups of the PrometheusRules of a namespace. 0 is unlimited.")
+	flag.IntVar(&quota.Namespace.MaxRules, "max-rules-per-namespace", 0,
+		"Maximum number of rules of the PrometheusRules of a namespace. 0 is unlimited.")
+	flag.IntVar(&quota.Namespace.MaxRulesPerGroup, "max-rules-per-group", 0,
+		"Maximum number of rules in a rule group. 0 is unlimited.")
+	flag.IntVar(&quota.Tenant.MaxGroups, "max-rule-groups-per-tenant", 0,
+		"Maximum number of rule groups of all PrometheusRules and ClusterPrometheusRules. 0 is unlimited.")
+	flag.IntVar(&quota.Tenant.MaxRules, "max-rules-per-tenant", 0,
+		"Maximum number of rules of all PrometheusRules and ClusterPrometheusRules. 0 is unlimited.")
+	flag.DurationVar(&testLimits.Timeout, "rule-test-timeout", ruletest.DefaultLimits.Timeout,
+		"Maximum duration of the unit tests of a PrometheusRule.")
+	flag.IntVar(&testLimits.MaxSeries, "rule-test-max-series", ruletest.DefaultLimits.MaxSeries,
+		"Maximum number of input series of a rule unit test.")
+	flag.IntVar(&testLimits.MaxSamples, "rule-test-max-samples", ruletest.DefaultLimits.MaxSamples,
+		"Maximum number of input samples of a rule unit test.")
+	flag.DurationVar(&testLimits.MaxEvalTime, "rule-test-max-eval-time", ruletest.DefaultLimits.MaxEvalTime,
+		"Latest eval_time a rule unit test may check.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -72,6 +132,13 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	switch monitoringv1.DeletionPolicy(deletionPolicy) {
+	case monitoringv1.DeletionPolicyDelete, monitoringv1.DeletionPolicyRetain, monitoringv1.DeletionPolicyOrphan:
+	default:
+		setupLog.Error(fmt.Errorf("unknown deletion policy %q", deletionPolicy), "invalid flags")
+		os.Exit(1)
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
 		MetricsBindAddress:     metricsAddr,
@@ -85,30 +152,119 @@ func main() {
 		os.Exit(1)
 	}
 
-	var newCortex *cortex.Client
-	{
-		c := cortex.Config{
-			Key:             cortexToken,
-			Address:         cortexURL,
-			ID:              cortexUser,
-			UseLegacyRoutes: false,
-		}
-		newCortex, err = cortex.New(c)
+	renderer, err := renderOpts.renderer(cortexOpts.user, lokiOpts.user)
+	if err != nil {
+		setupLog.Error(err, "unable to load federation policy")
+		os.Exit(1)
+	}
+
+	newCortex, err := cortexOpts.client(dryRun)
+	if err != nil {
+		setupLog.Error(err, "unable to create Cotex client")
+		os.Exit(1)
+	}
+
+	loki, err := lokiOpts.client(dryRun)
+	if err != nil {
+		setupLog.Error(err, "unable to create Loki client")
+		os.Exit(1)
+	}
+
+	ruleReconciler := &controllers.PrometheusRuleReconciler{
+		Client:         mgr.GetClient(),
+		Log:            ctrl.Log.WithName("controllers").WithName("PrometheusRule"),
+		Scheme:         mgr.GetScheme(),
+		Recorder:       mgr.GetEventRecorderFor("cortex-alert-operator"),
+		Cortex:         newCortex,
+		Loki:           loki,
+		Paused:         paused,
+		DeletionPolicy: monitoringv1.DeletionPolicy(deletionPolicy),
+		Renderer:       renderer,
+
+		HealthCheckInterval: healthCheckInterval,
+		Quota:               quota,
+		RulerLimits:         renderOpts.rulerLimits,
+		SplitGroups:         renderOpts.splitGroups,
+		TestLimits:          testLimits,
+	}
+	if err = ruleReconciler.SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "PrometheusRule")
+		os.Exit(1)
+	}
+	clusterRuleReconciler := &controllers.ClusterPrometheusRuleReconciler{PrometheusRuleReconciler: *ruleReconciler}
+	clusterRuleReconciler.Log = ctrl.Log.WithName("controllers").WithName("ClusterPrometheusRule")
+	if err = clusterRuleReconciler.SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ClusterPrometheusRule")
+		os.Exit(1)
+	}
+	if configMapSelector != "" {
+		selector, err := labels.Parse(configMapSelector)
 		if err != nil {
-			setupLog.Error(err, "unable to create Cotex client")
+			setupLog.Error(err, "invalid flags")
+			os.Exit(1)
+		}
+		if err = (&controllers.ConfigMapReconciler{
+			Client:   mgr.GetClient(),
+			Log:      ctrl.Log.WithName("controllers").WithName("ConfigMap"),
+			Scheme:   mgr.GetScheme(),
+			Recorder: mgr.GetEventRecorderFor("cortex-alert-operator"),
+			Selector: selector,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "ConfigMap")
 			os.Exit(1)
 		}
 	}
-
-	if err = (&controllers.PrometheusRuleReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("PrometheusRule"),
-		Scheme: mgr.GetScheme(),
-		Cortex: newCortex,
+	if err = (&controllers.AlertmanagerConfigReconciler{
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("controllers").WithName("AlertmanagerConfig"),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("cortex-alert-operator"),
+		Cortex:   newCortex,
+		Tenant:   cortexOpts.user,
+		Paused:   paused,
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "PrometheusRule")
+		setupLog.Error(err, "unable to create controller", "controller", "AlertmanagerConfig")
 		os.Exit(1)
 	}
+	if err = (&controllers.SilenceReconciler{
+		Client:         mgr.GetClient(),
+		Log:            ctrl.Log.WithName("controllers").WithName("Silence"),
+		Scheme:         mgr.GetScheme(),
+		Recorder:       mgr.GetEventRecorderFor("cortex-alert-operator"),
+		Cortex:         newCortex,
+		Paused:         paused,
+		ResyncInterval: silenceResyncInterval,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Silence")
+		os.Exit(1)
+	}
+	if enableWebhooks {
+		if err := defaultsOpts.Validate(); err != nil {
+			setupLog.Error(err, "invalid rule defaults")
+			os.Exit(1)
+		}
+		if err = (&controllers.RuleDefaulter{
+			Log:      ctrl.Log.WithName("webhooks").WithName("RuleDefaults"),
+			Defaults: defaultsOpts.Defaults,
+		}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "RuleDefaults")
+			os.Exit(1)
+		}
+		if err = (&controllers.RuleValidator{
+			Client:   mgr.GetClient(),
+			Log:      ctrl.Log.WithName("webhooks").WithName("Rule"),
+			Renderer: renderer,
+			Quota:    quota,
+
+			Cortex:      newCortex,
+			Loki:        loki,
+			RulerLimits: renderOpts.rulerLimits,
+			SplitGroups: renderOpts.splitGroups,
+		}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Rule")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
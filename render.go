@@ -0,0 +1,304 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	monitoringv1 "github.com/bolindalabs/cortex-alert-operator/api/v1"
+	"github.com/bolindalabs/cortex-alert-operator/controllers/cortex"
+	"github.com/bolindalabs/cortex-alert-operator/controllers/render"
+)
+
+// Formats of the rule files written by render.
+const (
+	// formatPrometheus is the Prometheus rule file format read by promtool, holding only the groups.
+	formatPrometheus = "prometheus"
+	// formatCortextool is the rule file format of cortextool, which adds the Cortex namespace.
+	formatCortextool = "cortextool"
+)
+
+// runRender reads PrometheusRule manifests from files or directories and writes
+// the Cortex rule files the operator would sync, one per Cortex namespace.
+// Only a single rule file can be written to stdout.
+func runRender(args []string) int {
+	fs := flag.NewFlagSet("render", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s render [flags] <file or directory>...\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	var renderOpts renderFlags
+	var defaultsOpts defaultsFlags
+	var tenant string
+	var lokiTenant string
+	var namespace string
+	var outputDir string
+	var backend string
+	var format string
+	renderOpts.bind(fs)
+	defaultsOpts.bind(fs)
+	fs.StringVar(&tenant, "cortex-user", "", "Cortex tenant the rules are written to.")
+	fs.StringVar(&lokiTenant, "loki-user", "", "Loki tenant the rules of the Loki backend are written to.")
+	fs.StringVar(&namespace, "namespace", "default", "Namespace of PrometheusRules that do not set one.")
+	fs.StringVar(&outputDir, "output-dir", "", "Directory to write the rule files to, named after their Cortex namespace. "+
+		"Defaults to stdout, which takes the rules of a single Cortex namespace only.")
+	fs.StringVar(&format, "format", formatPrometheus, "Format of the rule files, prometheus for promtool or cortextool.")
+	fs.StringVar(&backend, "backend", string(monitoringv1.BackendCortex), "Only render PrometheusRules of this backend, Cortex or Loki.")
+	_ = fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	if err := defaultsOpts.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid rule defaults: %v\n", err)
+		return 2
+	}
+	if format != formatPrometheus && format != formatCortextool {
+		fmt.Fprintf(os.Stderr, "invalid format %q, expected %s or %s\n", format, formatPrometheus, formatCortextool)
+		return 2
+	}
+	// Without Cortex to ask, the limit splitting depends on has to be given.
+	if renderOpts.splitGroups && renderOpts.rulerLimits.MaxRulesPerRuleGroup == 0 {
+		fmt.Fprintln(os.Stderr, "--split-oversized-groups requires --ruler-max-rules-per-rule-group")
+		return 2
+	}
+
+	log := ctrl.Log.WithName("render")
+
+	renderer, err := renderOpts.renderer(tenant, lokiTenant)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to load federation policy: %v\n", err)
+		return 2
+	}
+
+	var manifests ruleManifests
+	for _, path := range fs.Args() {
+		if err := manifests.read(path); err != nil {
+			fmt.Fprintf(os.Stderr, "unable to read %s: %v\n", path, err)
+			return 2
+		}
+	}
+	for i := range manifests.templates {
+		if manifests.templates[i].Namespace == "" {
+			manifests.templates[i].Namespace = namespace
+		}
+	}
+
+	var files []ruleFile
+	for _, rule := range manifests.rules {
+		if ruleBackend(rule) != monitoringv1.Backend(backend) {
+			continue
+		}
+		if pr, ok := rule.(*monitoringv1.PrometheusRule); ok && pr.Namespace == "" {
+			pr.Namespace = namespace
+		}
+		// The defaulting webhook sets the defaults before the rules are stored.
+		defaultsOpts.Apply(rule.RuleSpec())
+
+		expanded, err := render.ExpandTemplates(rule, manifests.templateLookup(rule.GetNamespace()))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "unable to expand templates of %s: %v\n", ruleRef(rule), err)
+			return 2
+		}
+
+		cortexNamespace, err := renderer.CortexNamespace(rule)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "unable to render %s: %v\n", ruleRef(rule), err)
+			return 2
+		}
+		result, err := renderer.Render(expanded, cortexNamespace)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "unable to render %s: %v\n", ruleRef(rule), err)
+			return 2
+		}
+		if result.Groups, err = renderOpts.split(log, nil, result.Groups); err != nil {
+			fmt.Fprintf(os.Stderr, "unable to split rule groups of %s: %v\n", ruleRef(rule), err)
+			return 2
+		}
+		if len(result.Groups) == 0 {
+			continue
+		}
+
+		ns := cortex.RuleNamespace{Namespace: cortexNamespace}
+		for _, g := range result.Groups {
+			ns.Groups = append(ns.Groups, cortex.NewRuleGroup(g))
+		}
+
+		var content interface{} = ns
+		if format == formatPrometheus {
+			content = cortex.RuleFile{Groups: ns.Groups}
+		}
+		out, err := yaml.Marshal(content)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "unable to marshal %s: %v\n", cortexNamespace, err)
+			return 2
+		}
+		files = append(files, ruleFile{cortexNamespace: cortexNamespace, content: out})
+	}
+
+	if outputDir == "" {
+		if len(files) > 1 {
+			fmt.Fprintf(os.Stderr, "the rules render to %d Cortex namespaces, set --output-dir to write a file for each\n", len(files))
+			return 2
+		}
+		for _, f := range files {
+			fmt.Print(string(f.content))
+		}
+		return 0
+	}
+
+	for _, f := range files {
+		if err := ioutil.WriteFile(filepath.Join(outputDir, f.cortexNamespace+".yaml"), f.content, 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "unable to write %s: %v\n", f.cortexNamespace, err)
+			return 2
+		}
+	}
+	return 0
+}
+
+// ruleFile is a rendered rule file and the Cortex namespace its rules are synced to.
+type ruleFile struct {
+	cortexNamespace string
+	content         []byte
+}
+
+// ruleBackend returns the backend of rule, defaulting to Cortex.
+func ruleBackend(rule monitoringv1.RuleObject) monitoringv1.Backend {
+	if rule.RuleSpec().Backend == "" {
+		return monitoringv1.BackendCortex
+	}
+	return rule.RuleSpec().Backend
+}
+
+// ruleRef identifies rule in messages, as namespace/name or, for cluster-scoped rules, name.
+func ruleRef(rule monitoringv1.RuleObject) string {
+	if rule.GetNamespace() == "" {
+		return rule.GetName()
+	}
+	return rule.GetNamespace() + "/" + rule.GetName()
+}
+
+// ruleManifests are the rules and rule templates read from manifest files.
+type ruleManifests struct {
+	rules     []monitoringv1.RuleObject
+	templates []monitoringv1.RuleTemplate
+}
+
+// templateLookup returns a lookup of the RuleTemplates read for namespace.
+func (m *ruleManifests) templateLookup(namespace string) render.TemplateLookup {
+	return func(name string) (*monitoringv1.RuleTemplate, error) {
+		for i, tmpl := range m.templates {
+			if tmpl.Namespace == namespace && tmpl.Name == name {
+				return &m.templates[i], nil
+			}
+		}
+		return nil, fmt.Errorf("rule template %s/%s not found", namespace, name)
+	}
+}
+
+// read adds the PrometheusRules, ClusterPrometheusRules and RuleTemplates in the YAML file at path,
+// or in the YAML files of the directory at path. Documents of other kinds are ignored.
+func (m *ruleManifests) read(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return m.decode(data)
+	}
+
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		ext := filepath.Ext(f.Name())
+		if f.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+
+		if err := m.read(filepath.Join(path, f.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decode adds the PrometheusRules, ClusterPrometheusRules and RuleTemplates of a multi-document YAML stream.
+func (m *ruleManifests) decode(data []byte) error {
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	for {
+		var doc json.RawMessage
+		if err := decoder.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		var typeMeta metav1.TypeMeta
+		if err := json.Unmarshal(doc, &typeMeta); err != nil {
+			return err
+		}
+		if !strings.HasPrefix(typeMeta.APIVersion, monitoringv1.GroupVersion.Group+"/") {
+			continue
+		}
+
+		var rule monitoringv1.RuleObject
+		switch typeMeta.Kind {
+		case "PrometheusRule":
+			rule = &monitoringv1.PrometheusRule{}
+		case "ClusterPrometheusRule":
+			rule = &monitoringv1.ClusterPrometheusRule{}
+		case "RuleTemplate":
+			var tmpl monitoringv1.RuleTemplate
+			if err := json.Unmarshal(doc, &tmpl); err != nil {
+				return err
+			}
+			m.templates = append(m.templates, tmpl)
+			continue
+		default:
+			continue
+		}
+		if err := json.Unmarshal(doc, rule); err != nil {
+			return err
+		}
+		m.rules = append(m.rules, rule)
+	}
+}